@@ -0,0 +1,541 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// -------- Health --------
+
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/healthz", nil, nil, nil)
+}
+
+// -------- Teams --------
+
+// AddTeam creates a team, creating or updating its members.
+func (c *Client) AddTeam(ctx context.Context, team Team) (Team, error) {
+	var resp struct {
+		Team Team `json:"team"`
+	}
+	err := c.do(ctx, http.MethodPost, "/team/add", nil, team, &resp)
+	return resp.Team, err
+}
+
+func (c *Client) GetTeam(ctx context.Context, teamName string) (Team, error) {
+	var team Team
+	err := c.do(ctx, http.MethodGet, "/team/get", url.Values{"team_name": {teamName}}, nil, &team)
+	return team, err
+}
+
+// DeactivateTeamUsers deactivates userIDs in the team (all members when
+// userIDs is empty) and reassigns their open reviews.
+func (c *Client) DeactivateTeamUsers(ctx context.Context, teamName string, userIDs []string) (DeactivateResult, error) {
+	req := map[string]any{"team_name": teamName}
+	if len(userIDs) > 0 {
+		req["user_ids"] = userIDs
+	}
+	var res DeactivateResult
+	err := c.do(ctx, http.MethodPost, "/team/deactivate", nil, req, &res)
+	return res, err
+}
+
+// Sync reconciles the listed teams (all teams with opts.AllTeams) with the
+// desired state in data, which has the Import format. Active members missing
+// from data are deactivated.
+func (c *Client) Sync(ctx context.Context, data []byte, contentType string, opts SyncOptions) (SyncResult, error) {
+	q := url.Values{}
+	q.Set("mode", "plan")
+	if opts.Apply {
+		q.Set("mode", "apply")
+	}
+	if opts.AllTeams {
+		q.Set("all_teams", "true")
+	}
+	var res SyncResult
+	err := c.send(ctx, http.MethodPost, "/team/sync", q, contentType, data, &res)
+	return res, err
+}
+
+func (c *Client) GetTeamSettings(ctx context.Context, teamName string) (TeamSettings, error) {
+	var resp struct {
+		Settings TeamSettings `json:"settings"`
+	}
+	err := c.do(ctx, http.MethodGet, "/team/getSettings", url.Values{"team_name": {teamName}}, nil, &resp)
+	return resp.Settings, err
+}
+
+func (c *Client) SetTeamSettings(ctx context.Context, teamName string, p TeamSettingsPatch) (TeamSettings, error) {
+	req := map[string]any{"team_name": teamName}
+	if p.ReviewSLASeconds != nil {
+		req["review_sla_seconds"] = *p.ReviewSLASeconds
+	}
+	if p.EscalateAfterSeconds != nil {
+		req["escalate_after_seconds"] = *p.EscalateAfterSeconds
+	}
+	if p.AssignmentStrategy != "" {
+		req["assignment_strategy"] = p.AssignmentStrategy
+	}
+	if p.ReviewerRules != nil {
+		req["reviewer_rules"] = *p.ReviewerRules
+	}
+	if p.PairAvoidancePRs != nil {
+		req["pair_avoidance_prs"] = *p.PairAvoidancePRs
+	}
+	if p.PreferWorkingHours != nil {
+		req["prefer_working_hours"] = *p.PreferWorkingHours
+	}
+	var resp struct {
+		Settings TeamSettings `json:"settings"`
+	}
+	err := c.do(ctx, http.MethodPost, "/team/setSettings", nil, req, &resp)
+	return resp.Settings, err
+}
+
+// -------- Users --------
+
+func (c *Client) SetUserActive(ctx context.Context, userID string, active bool) (User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+	err := c.do(ctx, http.MethodPost, "/users/setIsActive", nil, map[string]any{
+		"user_id":   userID,
+		"is_active": active,
+	}, &resp)
+	return resp.User, err
+}
+
+func (c *Client) GetUserSettings(ctx context.Context, userID string) (UserSettings, error) {
+	var resp struct {
+		Settings UserSettings `json:"settings"`
+	}
+	err := c.do(ctx, http.MethodGet, "/users/getSettings", url.Values{"user_id": {userID}}, nil, &resp)
+	return resp.Settings, err
+}
+
+func (c *Client) SetUserSettings(ctx context.Context, userID string, p UserSettingsPatch) (UserSettings, error) {
+	req := map[string]any{"user_id": userID}
+	if p.MaxOpenReviews != nil {
+		req["max_open_reviews"] = *p.MaxOpenReviews
+	}
+	if p.ReviewWeight != nil {
+		req["review_weight"] = *p.ReviewWeight
+	}
+	if p.Level != nil {
+		req["level"] = *p.Level
+	}
+	if p.Skills != nil {
+		req["skills"] = *p.Skills
+	}
+	if p.BlockedAuthors != nil {
+		req["blocked_authors"] = *p.BlockedAuthors
+	}
+	if p.BlockedLabels != nil {
+		req["blocked_labels"] = *p.BlockedLabels
+	}
+	if p.Timezone != nil {
+		req["timezone"] = *p.Timezone
+	}
+	if p.WorkStart != nil {
+		req["work_start"] = *p.WorkStart
+	}
+	if p.WorkEnd != nil {
+		req["work_end"] = *p.WorkEnd
+	}
+	var resp struct {
+		Settings UserSettings `json:"settings"`
+	}
+	err := c.do(ctx, http.MethodPost, "/users/setSettings", nil, req, &resp)
+	return resp.Settings, err
+}
+
+// GetUserReviews returns one page of pull requests assigned to userID for
+// review; status may be empty. The returned cursor is empty on the last page.
+func (c *Client) GetUserReviews(ctx context.Context, userID string, status PRStatus, p Page) ([]PullRequestShort, string, error) {
+	q := p.values()
+	q.Set("user_id", userID)
+	setNonEmpty(q, "status", string(status))
+	var resp struct {
+		PullRequests []PullRequestShort `json:"pull_requests"`
+		NextCursor   *string            `json:"next_cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/users/getReview", q, nil, &resp)
+	return resp.PullRequests, deref(resp.NextCursor), err
+}
+
+// -------- PRs --------
+
+// CreatePR creates a pull request with optional labels, which the team's
+// reviewer rules may depend on.
+func (c *Client) CreatePR(ctx context.Context, id, name, authorID string, labels ...string) (PullRequest, error) {
+	pr, _, err := c.CreatePRWithShortfall(ctx, id, name, authorID, labels...)
+	return pr, err
+}
+
+// CreatePRWithShortfall is CreatePR that also returns why fewer reviewers
+// than wanted were assigned, when that is because of reviewer capacity.
+func (c *Client) CreatePRWithShortfall(ctx context.Context, id, name, authorID string, labels ...string) (PullRequest, *CapacityShortfall, error) {
+	var resp struct {
+		PR                PullRequest        `json:"pr"`
+		CapacityShortfall *CapacityShortfall `json:"capacity_shortfall"`
+	}
+	req := map[string]any{
+		"pull_request_id":   id,
+		"pull_request_name": name,
+		"author_id":         authorID,
+	}
+	if len(labels) > 0 {
+		req["labels"] = labels
+	}
+	err := c.do(ctx, http.MethodPost, "/pullRequest/create", nil, req, &resp)
+	return resp.PR, resp.CapacityShortfall, err
+}
+
+func (c *Client) MergePR(ctx context.Context, id string) (PullRequest, error) {
+	var resp struct {
+		PR PullRequest `json:"pr"`
+	}
+	err := c.do(ctx, http.MethodPost, "/pullRequest/merge", nil, map[string]any{"pull_request_id": id}, &resp)
+	return resp.PR, err
+}
+
+// ReassignReviewer replaces oldUserID on the pull request and returns the
+// updated pull request together with the new reviewer.
+func (c *Client) ReassignReviewer(ctx context.Context, id, oldUserID string) (PullRequest, string, error) {
+	var resp struct {
+		PR         PullRequest `json:"pr"`
+		ReplacedBy string      `json:"replaced_by"`
+	}
+	err := c.do(ctx, http.MethodPost, "/pullRequest/reassign", nil, map[string]any{
+		"pull_request_id": id,
+		"old_user_id":     oldUserID,
+	}, &resp)
+	return resp.PR, resp.ReplacedBy, err
+}
+
+// SubmitReview records that userID reviewed the PR and returns the time of
+// their first review.
+func (c *Client) SubmitReview(ctx context.Context, id, userID string) (PullRequest, time.Time, error) {
+	var resp struct {
+		PR         PullRequest `json:"pr"`
+		ReviewedAt time.Time   `json:"reviewed_at"`
+	}
+	err := c.do(ctx, http.MethodPost, "/pullRequest/review", nil, map[string]any{
+		"pull_request_id": id,
+		"user_id":         userID,
+	}, &resp)
+	return resp.PR, resp.ReviewedAt, err
+}
+
+func (c *Client) GetPR(ctx context.Context, id string) (PullRequest, error) {
+	var resp struct {
+		PR PullRequest `json:"pr"`
+	}
+	err := c.do(ctx, http.MethodGet, "/pullRequest/get", url.Values{"pull_request_id": {id}}, nil, &resp)
+	return resp.PR, err
+}
+
+// ListPRs returns one page of pull requests matching f.
+func (c *Client) ListPRs(ctx context.Context, f PRFilter, p Page) ([]PullRequest, string, error) {
+	q := p.values()
+	setNonEmpty(q, "author_id", f.AuthorID)
+	setNonEmpty(q, "team_name", f.TeamName)
+	setNonEmpty(q, "reviewer_id", f.ReviewerID)
+	setNonEmpty(q, "status", string(f.Status))
+	setNonEmpty(q, "q", f.NameContains)
+	setTime(q, "merged_from", f.MergedFrom)
+	setTime(q, "merged_to", f.MergedTo)
+	var resp struct {
+		PullRequests []PullRequest `json:"pull_requests"`
+		NextCursor   *string       `json:"next_cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/pullRequest/list", q, nil, &resp)
+	return resp.PullRequests, deref(resp.NextCursor), err
+}
+
+// -------- Stats --------
+
+// UserStats returns one page of assignment counts per user.
+func (c *Client) UserStats(ctx context.Context, p Page) ([]UserAssignStat, string, error) {
+	q := p.values()
+	q.Set("by", "users")
+	var resp struct {
+		ByUsers    []UserAssignStat `json:"by_users"`
+		NextCursor *string          `json:"next_cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/get", q, nil, &resp)
+	return resp.ByUsers, deref(resp.NextCursor), err
+}
+
+// PRStats returns one page of assignment counts per pull request.
+func (c *Client) PRStats(ctx context.Context, p Page) ([]PRAssignStat, string, error) {
+	q := p.values()
+	q.Set("by", "prs")
+	var resp struct {
+		ByPRs      []PRAssignStat `json:"by_prs"`
+		NextCursor *string        `json:"next_cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/get", q, nil, &resp)
+	return resp.ByPRs, deref(resp.NextCursor), err
+}
+
+// TeamStats returns one page of assignment counts per team.
+func (c *Client) TeamStats(ctx context.Context, p Page) ([]TeamAssignStat, string, error) {
+	q := p.values()
+	q.Set("by", "teams")
+	var resp struct {
+		ByTeams    []TeamAssignStat `json:"by_teams"`
+		NextCursor *string          `json:"next_cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/get", q, nil, &resp)
+	return resp.ByTeams, deref(resp.NextCursor), err
+}
+
+// Fairness reports how evenly assignments in [from, to) are spread within
+// each team, or only within team when it is set. Zero times are omitted.
+func (c *Client) Fairness(ctx context.Context, team string, from, to time.Time) ([]TeamFairness, error) {
+	q := url.Values{}
+	setNonEmpty(q, "team_name", team)
+	setTime(q, "from", from)
+	setTime(q, "to", to)
+	var resp struct {
+		Teams []TeamFairness `json:"teams"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/fairness", q, nil, &resp)
+	return resp.Teams, err
+}
+
+// TeamLatency reports time to first review and time to merge per team for
+// the PRs created in [from, to); team and zero times are optional.
+func (c *Client) TeamLatency(ctx context.Context, team string, from, to time.Time) ([]TeamLatency, error) {
+	q := latencyQuery("teams", team, from, to)
+	var resp struct {
+		ByTeams []TeamLatency `json:"by_teams"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/latency", q, nil, &resp)
+	return resp.ByTeams, err
+}
+
+// ReviewerLatency reports time from assignment to review per reviewer for
+// the assignments made in [from, to).
+func (c *Client) ReviewerLatency(ctx context.Context, team string, from, to time.Time) ([]ReviewerLatency, error) {
+	q := latencyQuery("reviewers", team, from, to)
+	var resp struct {
+		ByReviewers []ReviewerLatency `json:"by_reviewers"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/latency", q, nil, &resp)
+	return resp.ByReviewers, err
+}
+
+func latencyQuery(by, team string, from, to time.Time) url.Values {
+	q := url.Values{"by": {by}}
+	setNonEmpty(q, "team_name", team)
+	setTime(q, "from", from)
+	setTime(q, "to", to)
+	return q
+}
+
+// UserSeries returns one page of per-user assignment time series; bucket is
+// BucketDay, BucketWeek or BucketMonth.
+func (c *Client) UserSeries(ctx context.Context, bucket string, p Page) ([]UserSeries, string, error) {
+	q := p.values()
+	q.Set("by", "users")
+	q.Set("bucket", bucket)
+	var resp struct {
+		Series     []UserSeries `json:"series_by_users"`
+		NextCursor *string      `json:"next_cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/get", q, nil, &resp)
+	return resp.Series, deref(resp.NextCursor), err
+}
+
+// TeamSeries is UserSeries per team.
+func (c *Client) TeamSeries(ctx context.Context, bucket string, p Page) ([]TeamSeries, string, error) {
+	q := p.values()
+	q.Set("by", "teams")
+	q.Set("bucket", bucket)
+	var resp struct {
+		Series     []TeamSeries `json:"series_by_teams"`
+		NextCursor *string      `json:"next_cursor"`
+	}
+	err := c.do(ctx, http.MethodGet, "/stats/get", q, nil, &resp)
+	return resp.Series, deref(resp.NextCursor), err
+}
+
+// -------- Admin --------
+
+// Import applies an org definition (see the /admin/import docs) given as
+// YAML, JSON or CSV; contentType is e.g. "application/yaml" or "text/csv".
+// With dryRun the server reports the changes without keeping them.
+func (c *Client) Import(ctx context.Context, data []byte, contentType string, dryRun bool) (ImportResult, error) {
+	q := url.Values{}
+	if dryRun {
+		q.Set("dry_run", "true")
+	}
+	var res ImportResult
+	err := c.send(ctx, http.MethodPost, "/admin/import", q, contentType, data, &res)
+	return res, err
+}
+
+// Export streams a database snapshot (NDJSON, see /admin/export) to w. It is
+// not retried, and fails if the snapshot ends without its footer line.
+func (c *Client) Export(ctx context.Context, w io.Writer) (SnapshotCounts, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/admin/export", nil)
+	if err != nil {
+		return SnapshotCounts{}, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return SnapshotCounts{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		return SnapshotCounts{}, decodeError(resp.StatusCode, data)
+	}
+
+	br := bufio.NewReader(resp.Body)
+	var last []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, werr := w.Write(line); werr != nil {
+				return SnapshotCounts{}, werr
+			}
+			last = line
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return SnapshotCounts{}, err
+		}
+	}
+
+	var footer struct {
+		Type string `json:"type"`
+		Data struct {
+			Counts SnapshotCounts `json:"counts"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(last, &footer) != nil || footer.Type != "footer" {
+		return SnapshotCounts{}, errors.New("client: export is truncated (no footer)")
+	}
+	return footer.Data.Counts, nil
+}
+
+// RestoreSnapshot loads a snapshot produced by Export into an empty server.
+func (c *Client) RestoreSnapshot(ctx context.Context, data []byte) (SnapshotCounts, error) {
+	var resp struct {
+		Restored SnapshotCounts `json:"restored"`
+	}
+	err := c.send(ctx, http.MethodPost, "/admin/import-snapshot", nil, "application/x-ndjson", data, &resp)
+	return resp.Restored, err
+}
+
+// ListJobs returns the background jobs and their latest runs.
+func (c *Client) ListJobs(ctx context.Context) (Jobs, error) {
+	var resp Jobs
+	err := c.do(ctx, http.MethodGet, "/admin/jobs", nil, nil, &resp)
+	return resp, err
+}
+
+// JobRuns returns up to limit latest runs, newest first, of the named job or
+// of all jobs when name is empty; limit 0 means the server default.
+func (c *Client) JobRuns(ctx context.Context, name string, limit int) ([]JobRun, error) {
+	q := url.Values{}
+	setNonEmpty(q, "name", name)
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var resp struct {
+		Runs []JobRun `json:"runs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/jobs/runs", q, nil, &resp)
+	return resp.Runs, err
+}
+
+// TriggerJob starts a run of the named job and returns it while it runs. It
+// fails with ErrJobRunning when the job is running already.
+func (c *Client) TriggerJob(ctx context.Context, name string) (JobRun, error) {
+	var resp struct {
+		Run JobRun `json:"run"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/jobs/trigger", nil, map[string]any{"name": name}, &resp)
+	return resp.Run, err
+}
+
+// -------- GraphQL --------
+
+// GraphQLError is an entry of the errors array of a GraphQL response.
+type GraphQLError struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// GraphQL runs query and decodes its data into out. Field errors reported by
+// the server are returned alongside the (partial) data.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, out any) ([]GraphQLError, error) {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors"`
+	}
+	// Queries are read-only, storing their responses for replay is pointless.
+	ctx = WithIdempotencyKey(ctx, "")
+	err := c.do(ctx, http.MethodPost, "/graphql", nil, map[string]any{
+		"query":     query,
+		"variables": variables,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if out != nil && len(resp.Data) > 0 && string(resp.Data) != "null" {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return resp.Errors, err
+		}
+	}
+	return resp.Errors, nil
+}
+
+// -------- helpers --------
+
+func (p Page) values() url.Values {
+	q := url.Values{}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	setNonEmpty(q, "cursor", p.Cursor)
+	setNonEmpty(q, "sort", p.Sort)
+	setNonEmpty(q, "order", p.Order)
+	setTime(q, "from", p.From)
+	setTime(q, "to", p.To)
+	return q
+}
+
+func setNonEmpty(q url.Values, name, v string) {
+	if v != "" {
+		q.Set(name, v)
+	}
+}
+
+func setTime(q url.Values, name string, t time.Time) {
+	if !t.IsZero() {
+		q.Set(name, t.UTC().Format(time.RFC3339))
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,215 @@
+// Package client is a Go client for the reviewer-service HTTP API.
+//
+// POST requests carry an Idempotency-Key, generated per call unless one is set
+// with WithIdempotencyKey, so they are safe to retry: transport failures, 5xx
+// and 429 responses and IDEMPOTENCY_IN_PROGRESS conflicts are retried with
+// exponential backoff. Error responses are returned as *APIError, which
+// matches the package sentinels (ErrPRMerged, ErrNoCandidate, ...) with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetries    = 3
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 2 * time.Second
+
+	idempotencyHeader = "Idempotency-Key"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	userAgent  string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how many times a failed request is retried; 0 disables retries.
+func WithRetries(n int) Option {
+	return func(c *Client) { c.retries = n }
+}
+
+// WithBackoff sets the delay before the first retry and the cap for later ones.
+func WithBackoff(base, maxDelay time.Duration) Option {
+	return func(c *Client) { c.backoff, c.maxBackoff = base, maxDelay }
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client for the API at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base URL %q", baseURL)
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+		userAgent:  "reviewer-service-go-client",
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c, nil
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey makes POST requests issued with ctx use key instead of a
+// generated one, so that a retry across process restarts is still deduplicated.
+// An empty key sends no header at all.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+	return c.send(ctx, method, path, query, "application/json", payload, out)
+}
+
+// send issues a request with a pre-encoded body, retrying as described in the
+// package documentation, and decodes a JSON response into out.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, contentType string, payload []byte, out any) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	key := ""
+	if method == http.MethodPost {
+		var ok bool
+		if key, ok = ctx.Value(idempotencyKey{}).(string); !ok {
+			key = newIdempotencyKey()
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := c.attempt(ctx, method, target, key, contentType, payload, out)
+		if err == nil || !retry || attempt >= c.retries {
+			return err
+		}
+		t := time.NewTimer(c.delay(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// attempt performs a single request and reports whether a failure is worth retrying.
+func (c *Client) attempt(ctx context.Context, method, target, key, contentType string, payload []byte, out any) (bool, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if key != "" {
+		req.Header.Set(idempotencyHeader, key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := decodeError(resp.StatusCode, data)
+		retry := resp.StatusCode >= 500 ||
+			resp.StatusCode == http.StatusTooManyRequests ||
+			errors.Is(apiErr, ErrIdempotencyInProgress)
+		return retry, apiErr
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return false, fmt.Errorf("client: decode response: %w", err)
+		}
+	}
+	return false, nil
+}
+
+func decodeError(status int, data []byte) *APIError {
+	var env struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil || env.Error == nil || env.Error.Code == "" {
+		code := "INTERNAL"
+		if status < 500 {
+			code = strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+		}
+		return &APIError{StatusCode: status, Code: code, Message: strings.TrimSpace(string(data))}
+	}
+	env.Error.StatusCode = status
+	return env.Error
+}
+
+// delay returns an exponential backoff with full jitter for the given retry.
+func (c *Client) delay(attempt int) time.Duration {
+	d := c.backoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = cryptorand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,154 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"reviewer-service/client"
+	"reviewer-service/internal/config"
+	"reviewer-service/internal/db"
+	httpx "reviewer-service/internal/http"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+func newClient(t *testing.T, url string) *client.Client {
+	t.Helper()
+	c, err := client.New(url, client.WithBackoff(time.Millisecond, 5*time.Millisecond))
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_RetriesPOSTWithSameIdempotencyKey(t *testing.T) {
+	var calls atomic.Int32
+	keys := make(chan string, 3)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get("Idempotency-Key")
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"code":"INTERNAL","message":"internal error"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"pr":{"pull_request_id":"pr1","status":"OPEN","assigned_reviewers":["u2"]}}`))
+	}))
+	defer ts.Close()
+
+	pr, err := newClient(t, ts.URL).CreatePR(context.Background(), "pr1", "PR", "u1")
+	require.NoError(t, err)
+	require.Equal(t, "pr1", pr.PullRequestID)
+	require.Equal(t, int32(3), calls.Load())
+
+	first := <-keys
+	require.NotEmpty(t, first)
+	require.Equal(t, first, <-keys)
+	require.Equal(t, first, <-keys)
+}
+
+func TestClient_DoesNotRetryBusinessErrors(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error":{"code":"PR_MERGED","message":"cannot reassign on merged PR"}}`))
+	}))
+	defer ts.Close()
+
+	_, _, err := newClient(t, ts.URL).ReassignReviewer(context.Background(), "pr1", "u2")
+	require.ErrorIs(t, err, client.ErrPRMerged)
+	require.NotErrorIs(t, err, client.ErrNoCandidate)
+
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_StopsRetryingWhenContextIsDone(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	c, err := client.New(ts.URL, client.WithRetries(100), client.WithBackoff(50*time.Millisecond, time.Second))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = c.GetTeam(ctx, "backend")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_AgainstRouter(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	cfg := config.FromEnv()
+	pool, err := db.NewPool(context.Background(), cfg.DatabaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	spec, err := httpx.LoadSpec(context.Background(), "../openapi.yml")
+	require.NoError(t, err)
+	handler, err := httpx.NewRouter(service.New(repo.New(pool)), httpx.Options{
+		Spec:              spec,
+		ValidateResponses: true,
+	})
+	require.NoError(t, err)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	c := newClient(t, ts.URL)
+	ctx := context.Background()
+	require.NoError(t, c.Health(ctx))
+
+	_, err = c.AddTeam(ctx, client.Team{
+		TeamName: "sdk",
+		Members: []client.TeamMember{
+			{UserID: "k1", Username: "K1", IsActive: true},
+			{UserID: "k2", Username: "K2", IsActive: true},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = c.AddTeam(ctx, client.Team{TeamName: "sdk", Members: []client.TeamMember{}})
+	require.ErrorIs(t, err, client.ErrTeamExists)
+
+	pr, err := c.CreatePR(ctx, "pr-sdk", "SDK PR", "k1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"k2"}, pr.AssignedReviewers)
+
+	reviews, next, err := c.GetUserReviews(ctx, "k2", client.PROpen, client.Page{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, next)
+	require.Len(t, reviews, 1)
+
+	_, _, err = c.ReassignReviewer(ctx, "pr-sdk", "k2")
+	require.ErrorIs(t, err, client.ErrNoCandidate)
+
+	merged, err := c.MergePR(ctx, "pr-sdk")
+	require.NoError(t, err)
+	require.Equal(t, client.PRMerged, merged.Status)
+
+	_, _, err = c.ReassignReviewer(ctx, "pr-sdk", "k2")
+	require.ErrorIs(t, err, client.ErrPRMerged)
+
+	_, err = c.GetPR(ctx, "missing")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = c.CreatePR(ctx, "", "no id", "k1")
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.ErrorIs(t, err, client.ErrValidation)
+	require.NotEmpty(t, apiErr.Details)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors mirror the API error codes. An *APIError matches the
+// sentinel for its code with errors.Is, e.g. errors.Is(err, client.ErrPRMerged).
+var (
+	ErrTeamExists  = errors.New("TEAM_EXISTS")
+	ErrUserExists  = errors.New("USER_EXISTS")
+	ErrPRExists    = errors.New("PR_EXISTS")
+	ErrPRMerged    = errors.New("PR_MERGED")
+	ErrNotAssigned = errors.New("NOT_ASSIGNED")
+	ErrNoCandidate = errors.New("NO_CANDIDATE")
+	ErrNotFound    = errors.New("NOT_FOUND")
+
+	ErrIdempotencyConflict   = errors.New("IDEMPOTENCY_CONFLICT")
+	ErrIdempotencyInProgress = errors.New("IDEMPOTENCY_IN_PROGRESS")
+
+	ErrValidation = errors.New("VALIDATION_ERROR")
+	ErrConflict   = errors.New("CONFLICT")
+	ErrNotEmpty   = errors.New("NOT_EMPTY")
+	ErrJobRunning = errors.New("JOB_RUNNING")
+	ErrInternal   = errors.New("INTERNAL")
+
+	ErrRulesUnsatisfiable = errors.New("RULES_UNSATISFIABLE")
+)
+
+var codeErrors = map[string]error{}
+
+func init() {
+	for _, err := range []error{
+		ErrTeamExists, ErrUserExists, ErrPRExists, ErrPRMerged, ErrNotAssigned, ErrNoCandidate, ErrNotFound,
+		ErrIdempotencyConflict, ErrIdempotencyInProgress, ErrValidation, ErrConflict, ErrNotEmpty, ErrJobRunning,
+		ErrRulesUnsatisfiable, ErrInternal,
+	} {
+		codeErrors[err.Error()] = err
+	}
+}
+
+// FieldError describes a single invalid request field.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// APIError is an error response returned by the server.
+type APIError struct {
+	StatusCode int          `json:"-"`
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	Details    []FieldError `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s (%d): %s", e.Code, e.StatusCode, e.Message)
+	if len(e.Details) > 0 {
+		parts := make([]string, 0, len(e.Details))
+		for _, f := range e.Details {
+			parts = append(parts, f.Field+": "+f.Reason)
+		}
+		msg += " [" + strings.Join(parts, "; ") + "]"
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	sentinel, ok := codeErrors[e.Code]
+	return ok && sentinel == target
+}
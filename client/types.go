@@ -0,0 +1,334 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TeamMember struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	IsActive bool   `json:"is_active"`
+}
+
+type Team struct {
+	TeamName string       `json:"team_name"`
+	Members  []TeamMember `json:"members"`
+}
+
+type AssignmentStrategy string
+
+const (
+	StrategyRandom     AssignmentStrategy = "RANDOM"
+	StrategyRoundRobin AssignmentStrategy = "ROUND_ROBIN"
+)
+
+// ReviewerRule requires at least one reviewer with Level or with Skill
+// (exactly one is set), on PRs labeled IfLabel or on all PRs.
+type ReviewerRule struct {
+	Level   string `json:"level,omitempty"`
+	Skill   string `json:"skill,omitempty"`
+	IfLabel string `json:"if_label,omitempty"`
+}
+
+// TeamSettings are per-team options; nil means off. RotationCursor is the
+// member picked last by round-robin. PairAvoidancePRs is how many of the
+// author's latest PRs selection looks back at to avoid repeating reviewers;
+// PreferWorkingHours favors reviewers within their working hours.
+type TeamSettings struct {
+	TeamName             string             `json:"team_name"`
+	ReviewSLASeconds     *int               `json:"review_sla_seconds"`
+	EscalateAfterSeconds *int               `json:"escalate_after_seconds"`
+	AssignmentStrategy   AssignmentStrategy `json:"assignment_strategy"`
+	RotationCursor       *string            `json:"rotation_cursor"`
+	ReviewerRules        []ReviewerRule     `json:"reviewer_rules"`
+	PairAvoidancePRs     *int               `json:"pair_avoidance_prs"`
+	PreferWorkingHours   bool               `json:"prefer_working_hours"`
+}
+
+// TeamSettingsPatch lists the settings to change: nil fields are kept, zero
+// turns a setting off. An empty AssignmentStrategy is kept too; ReviewerRules
+// replaces all rules.
+type TeamSettingsPatch struct {
+	ReviewSLASeconds     *int
+	EscalateAfterSeconds *int
+	AssignmentStrategy   AssignmentStrategy
+	ReviewerRules        *[]ReviewerRule
+	PairAvoidancePRs     *int
+	PreferWorkingHours   *bool
+}
+
+// UserSettings are per-user reviewer options; nil means no limit.
+// ReviewWeight is the relative chance of being picked, 0 for never. The user
+// is never picked for PRs by BlockedAuthors or with any of BlockedLabels.
+// WorkStart and WorkEnd are "HH:MM" in Timezone, UTC when nil.
+type UserSettings struct {
+	UserID         string   `json:"user_id"`
+	MaxOpenReviews *int     `json:"max_open_reviews"`
+	ReviewWeight   int      `json:"review_weight"`
+	Level          *string  `json:"level"`
+	Skills         []string `json:"skills"`
+	BlockedAuthors []string `json:"blocked_authors"`
+	BlockedLabels  []string `json:"blocked_labels"`
+	Timezone       *string  `json:"timezone"`
+	WorkStart      *string  `json:"work_start"`
+	WorkEnd        *string  `json:"work_end"`
+}
+
+// UserSettingsPatch lists the settings to change: nil fields are kept, zero
+// removes a limit, an empty Level removes the level; Skills, BlockedAuthors
+// and BlockedLabels replace the whole list. An empty Timezone means UTC,
+// empty WorkStart and WorkEnd remove the working hours.
+type UserSettingsPatch struct {
+	MaxOpenReviews *int
+	ReviewWeight   *int
+	Level          *string
+	Skills         *[]string
+	BlockedAuthors *[]string
+	BlockedLabels  *[]string
+	Timezone       *string
+	WorkStart      *string
+	WorkEnd        *string
+}
+
+type User struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	TeamName string `json:"team_name"`
+	IsActive bool   `json:"is_active"`
+}
+
+type PRStatus string
+
+const (
+	PROpen   PRStatus = "OPEN"
+	PRMerged PRStatus = "MERGED"
+)
+
+type PullRequest struct {
+	PullRequestID     string     `json:"pull_request_id"`
+	PullRequestName   string     `json:"pull_request_name"`
+	AuthorID          string     `json:"author_id"`
+	Status            PRStatus   `json:"status"`
+	AssignedReviewers []string   `json:"assigned_reviewers"`
+	Labels            []string   `json:"labels,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	MergedAt          *time.Time `json:"mergedAt,omitempty"`
+}
+
+type PullRequestShort struct {
+	PullRequestID   string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	Status          PRStatus `json:"status"`
+}
+
+type SafeReassignStats struct {
+	Reassigned int `json:"reassigned"`
+	Removed    int `json:"removed"`
+	// RemovedAtCapacity counts the removals with teammates left who were all
+	// at their max_open_reviews.
+	RemovedAtCapacity int `json:"removed_at_capacity"`
+}
+
+// CapacityShortfall tells that a PR got fewer reviewers than wanted because
+// the members in AtCapacity were at their max_open_reviews.
+type CapacityShortfall struct {
+	Wanted     int      `json:"wanted"`
+	Assigned   int      `json:"assigned"`
+	AtCapacity []string `json:"at_capacity"`
+}
+
+type DeactivateResult struct {
+	TeamName     string            `json:"team_name"`
+	Deactivated  []string          `json:"deactivated"`
+	SafeReassign SafeReassignStats `json:"safe_reassign"`
+}
+
+type ImportChange struct {
+	Action   string   `json:"action"`
+	TeamName string   `json:"team_name"`
+	UserID   string   `json:"user_id,omitempty"`
+	FromTeam string   `json:"from_team,omitempty"`
+	Fields   []string `json:"fields,omitempty"`
+}
+
+type ImportSummary struct {
+	TeamsCreated     int `json:"teams_created"`
+	UsersCreated     int `json:"users_created"`
+	UsersUpdated     int `json:"users_updated"`
+	UsersMoved       int `json:"users_moved"`
+	UsersDeactivated int `json:"users_deactivated"`
+}
+
+type ImportResult struct {
+	DryRun       bool              `json:"dry_run"`
+	Changes      []ImportChange    `json:"changes"`
+	Summary      ImportSummary     `json:"summary"`
+	SafeReassign SafeReassignStats `json:"safe_reassign"`
+}
+
+type SyncResult struct {
+	// Mode is "plan" or "apply".
+	Mode         string            `json:"mode"`
+	Changes      []ImportChange    `json:"changes"`
+	Summary      ImportSummary     `json:"summary"`
+	SafeReassign SafeReassignStats `json:"safe_reassign"`
+}
+
+// SyncOptions controls Sync. The zero value only computes the plan for the
+// teams listed in the document.
+type SyncOptions struct {
+	Apply bool
+	// AllTeams treats the document as the whole org, so teams missing from it
+	// lose all active members.
+	AllTeams bool
+}
+
+type SnapshotCounts struct {
+	Teams        int64 `json:"teams"`
+	Users        int64 `json:"users"`
+	PullRequests int64 `json:"pull_requests"`
+	Reviewers    int64 `json:"reviewers"`
+	Assignments  int64 `json:"assignments"`
+}
+
+// JobRun is one run of a background job; Result is job specific.
+type JobRun struct {
+	ID         int64           `json:"id"`
+	Job        string          `json:"job"`
+	Trigger    string          `json:"trigger"`
+	Instance   string          `json:"instance"`
+	Status     string          `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+	Result     json.RawMessage `json:"result"`
+	Error      *string         `json:"error"`
+}
+
+type Job struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// IntervalSeconds is 0 for jobs that only run when triggered.
+	IntervalSeconds int        `json:"interval_seconds"`
+	LastRun         *JobRun    `json:"last_run"`
+	NextRunAt       *time.Time `json:"next_run_at"`
+}
+
+// Jobs is the answer of the server instance that served the request: Leader
+// tells whether that instance runs the schedule.
+type Jobs struct {
+	Instance string `json:"instance"`
+	Leader   bool   `json:"leader"`
+	Jobs     []Job  `json:"jobs"`
+}
+
+// Job run statuses.
+const (
+	JobRunning   = "RUNNING"
+	JobSucceeded = "SUCCEEDED"
+	JobFailed    = "FAILED"
+)
+
+type UserAssignStat struct {
+	UserID string `json:"user_id"`
+	Count  int64  `json:"count"`
+}
+
+type PRAssignStat struct {
+	PullRequestID string `json:"pull_request_id"`
+	Count         int64  `json:"count"`
+}
+
+type TeamAssignStat struct {
+	TeamName string `json:"team_name"`
+	Count    int64  `json:"count"`
+}
+
+// TeamFairness is the spread of a team's assignments over its active
+// members; MaxMinRatio is nil when a member got none.
+type TeamFairness struct {
+	TeamName      string           `json:"team_name"`
+	ActiveMembers int              `json:"active_members"`
+	Assignments   int64            `json:"assignments"`
+	Mean          float64          `json:"mean"`
+	StdDev        float64          `json:"stddev"`
+	Gini          float64          `json:"gini"`
+	Min           int64            `json:"min"`
+	Max           int64            `json:"max"`
+	MaxMinRatio   *float64         `json:"max_min_ratio"`
+	Members       []UserAssignStat `json:"members"`
+}
+
+// Latency summarizes durations in seconds; the values are nil when Count is
+// zero.
+type Latency struct {
+	Count int64    `json:"count"`
+	Mean  *float64 `json:"mean"`
+	P50   *float64 `json:"p50"`
+	P90   *float64 `json:"p90"`
+	P95   *float64 `json:"p95"`
+}
+
+type TeamLatency struct {
+	TeamName          string  `json:"team_name"`
+	PRs               int64   `json:"prs"`
+	TimeToFirstReview Latency `json:"time_to_first_review"`
+	TimeToMerge       Latency `json:"time_to_merge"`
+}
+
+type ReviewerLatency struct {
+	UserID       string  `json:"user_id"`
+	TeamName     string  `json:"team_name"`
+	Assigned     int64   `json:"assigned"`
+	Pending      int64   `json:"pending"`
+	TimeToReview Latency `json:"time_to_review"`
+}
+
+// StatsPoint is the number of assignments in the bucket starting at Start.
+type StatsPoint struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+type UserSeries struct {
+	UserID string       `json:"user_id"`
+	Total  int64        `json:"total"`
+	Points []StatsPoint `json:"points"`
+}
+
+type TeamSeries struct {
+	TeamName string       `json:"team_name"`
+	Total    int64        `json:"total"`
+	Points   []StatsPoint `json:"points"`
+}
+
+// Buckets for UserSeries and TeamSeries.
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// Page holds the common list parameters. Zero values are omitted, so the
+// server defaults apply.
+type Page struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	// Order is "asc" or "desc".
+	Order string
+	From  time.Time
+	To    time.Time
+}
+
+// PRFilter narrows ListPRs; Page.From/To bound the creation time.
+type PRFilter struct {
+	AuthorID     string
+	TeamName     string
+	ReviewerID   string
+	Status       PRStatus
+	NameContains string
+	MergedFrom   time.Time
+	MergedTo     time.Time
+}
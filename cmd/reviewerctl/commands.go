@@ -0,0 +1,949 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"reviewer-service/client"
+)
+
+type command struct {
+	summary string
+	run     func(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error)
+}
+
+var commands = map[string]map[string]command{
+	"team": {
+		"add":        {"create a team: -name N -member id:username[:inactive]...", teamAdd},
+		"get":        {"show team members: -name N", teamGet},
+		"deactivate": {"deactivate members and reassign their reviews: -name N [-user id]...", teamDeactivate},
+		"sync":       {"reconcile teams with a desired state: -f FILE [-apply] [-all-teams]", teamSync},
+		"settings":   {"show or change team settings: -name N [-review-sla D] [-escalate-after D] [-strategy S] [-rules R] [-avoid-pairs N] [-prefer-working-hours]", teamSettings},
+	},
+	"user": {
+		"activate":   {"mark a user active: -id U", userSetActive(true)},
+		"deactivate": {"mark a user inactive: -id U", userSetActive(false)},
+		"reviews":    {"list PRs assigned to a user: -id U [-status OPEN|MERGED]", userReviews},
+		"settings":   {"show or change reviewer settings: -id U [-max-open-reviews N] [-weight N] [-level L] [-skills S] [-block-authors A] [-block-labels L] [-timezone Z] [-hours H]", userSettings},
+	},
+	"pr": {
+		"create":   {"create a PR and assign reviewers: -id P -name N -author U [-labels L]", prCreate},
+		"merge":    {"merge a PR: -id P", prMerge},
+		"reassign": {"replace a reviewer: -id P -old U", prReassign},
+		"review":   {"record that a reviewer reviewed a PR: -id P -user U", prReview},
+		"get":      {"show a PR: -id P", prGet},
+	},
+	"admin": {
+		"import":  {"import teams and users from YAML/JSON/CSV: -f FILE [-dry-run]", adminImport},
+		"export":  {"write a full database snapshot: -f FILE", adminExport},
+		"restore": {"load a snapshot into an empty instance: -f FILE", adminRestore},
+		"jobs":    {"list background jobs with their last runs", adminJobs},
+		"runs":    {"background job run history [-name N -limit N]", adminJobRuns},
+		"trigger": {"run a background job now: -name N", adminTrigger},
+	},
+	"stats": {
+		"users":    {"assignment counts per user [-limit N -from T -to T -all] [-bucket day|week|month]", statsUsers},
+		"prs":      {"assignment counts per PR [-limit N -from T -to T -all]", statsPRs},
+		"teams":    {"assignment counts per team [-limit N -from T -to T -all] [-bucket day|week|month]", statsTeams},
+		"fairness": {"spread of assignments within teams [-team T -from T -to T]", statsFairness},
+		"latency":  {"review and merge latency percentiles [-by teams|reviewers -team T -from T -to T]", statsLatency},
+	},
+}
+
+// multiFlag collects a repeatable string flag.
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+// parse parses command flags. The flag package has already reported a parse
+// error together with the usage, so it is collapsed into flag.ErrHelp.
+func parse(fs *flag.FlagSet, args []string, required map[string]*string) error {
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	var missing []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if v, ok := required[f.Name]; ok && *v == "" {
+			missing = append(missing, "-"+f.Name)
+		}
+	})
+	if len(missing) > 0 {
+		return usagef("%s required", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// -------- team --------
+
+func teamAdd(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	name := fs.String("name", "", "team name")
+	var members multiFlag
+	fs.Var(&members, "member", "member as id:username[:inactive], repeatable")
+	if err := parse(fs, args, map[string]*string{"name": name}); err != nil {
+		return result{}, err
+	}
+
+	team := client.Team{TeamName: *name, Members: []client.TeamMember{}}
+	for _, m := range members {
+		parts := strings.Split(m, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" ||
+			(len(parts) == 3 && parts[2] != "inactive") {
+			return result{}, usagef("-member %q: want id:username[:inactive]", m)
+		}
+		team.Members = append(team.Members, client.TeamMember{
+			UserID:   parts[0],
+			Username: parts[1],
+			IsActive: len(parts) == 2,
+		})
+	}
+
+	created, err := c.AddTeam(ctx, team)
+	if err != nil {
+		return result{}, err
+	}
+	return teamResult(created), nil
+}
+
+func teamGet(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	name := fs.String("name", "", "team name")
+	if err := parse(fs, args, map[string]*string{"name": name}); err != nil {
+		return result{}, err
+	}
+	team, err := c.GetTeam(ctx, *name)
+	if err != nil {
+		return result{}, err
+	}
+	return teamResult(team), nil
+}
+
+func teamDeactivate(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	name := fs.String("name", "", "team name")
+	var users multiFlag
+	fs.Var(&users, "user", "user id to deactivate, repeatable (default: whole team)")
+	if err := parse(fs, args, map[string]*string{"name": name}); err != nil {
+		return result{}, err
+	}
+	res, err := c.DeactivateTeamUsers(ctx, *name, users)
+	if err != nil {
+		return result{}, err
+	}
+	return result{
+		raw:    res,
+		header: []string{"team_name", "deactivated", "reassigned", "removed", "removed_at_capacity"},
+		rows: [][]string{{
+			res.TeamName,
+			strings.Join(res.Deactivated, " "),
+			strconv.Itoa(res.SafeReassign.Reassigned),
+			strconv.Itoa(res.SafeReassign.Removed),
+			strconv.Itoa(res.SafeReassign.RemovedAtCapacity),
+		}},
+	}, nil
+}
+
+func teamSync(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	file, format := orgFileFlags(fs)
+	apply := fs.Bool("apply", false, "apply the changes (default: only show the plan)")
+	allTeams := fs.Bool("all-teams", false, "treat the file as the whole org: teams missing from it are emptied")
+	if err := parse(fs, args, map[string]*string{"f": file}); err != nil {
+		return result{}, err
+	}
+	data, contentType, err := readOrgFile(*file, *format)
+	if err != nil {
+		return result{}, err
+	}
+	res, err := c.Sync(ctx, data, contentType, client.SyncOptions{Apply: *apply, AllTeams: *allTeams})
+	if err != nil {
+		return result{}, err
+	}
+	return changesResult(res, res.Changes), nil
+}
+
+func teamSettings(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	name := fs.String("name", "", "team name")
+	sla := fs.Duration("review-sla", 0, "remind reviewers after this long without a review, 0 turns it off")
+	escalate := fs.Duration("escalate-after", 0, "reassign reviews after this long without a review, 0 turns it off")
+	strategy := fs.String("strategy", "", "how reviewers are picked: RANDOM or ROUND_ROBIN")
+	rules := fs.String("rules", "", "comma-separated reviewer rules as level=L or skill=S, with :LABEL to apply only to PRs labeled so; empty removes all")
+	avoidPairs := fs.Int("avoid-pairs", 0, "prefer reviewers not assigned to the author's last N PRs, 0 turns it off")
+	preferWorking := fs.Bool("prefer-working-hours", false, "prefer reviewers within their working hours")
+	if err := parse(fs, args, map[string]*string{"name": name}); err != nil {
+		return result{}, err
+	}
+
+	var p client.TeamSettingsPatch
+	changed, rulesSet := false, false
+	fs.Visit(func(f *flag.Flag) {
+		seconds := func(d time.Duration) *int { n := int(d / time.Second); return &n }
+		switch f.Name {
+		case "review-sla":
+			p.ReviewSLASeconds, changed = seconds(*sla), true
+		case "escalate-after":
+			p.EscalateAfterSeconds, changed = seconds(*escalate), true
+		case "strategy":
+			p.AssignmentStrategy, changed = client.AssignmentStrategy(strings.ToUpper(*strategy)), true
+		case "rules":
+			rulesSet, changed = true, true
+		case "avoid-pairs":
+			p.PairAvoidancePRs, changed = avoidPairs, true
+		case "prefer-working-hours":
+			p.PreferWorkingHours, changed = preferWorking, true
+		}
+	})
+	if rulesSet {
+		parsed, err := parseRules(*rules)
+		if err != nil {
+			return result{}, err
+		}
+		p.ReviewerRules = &parsed
+	}
+
+	var ts client.TeamSettings
+	var err error
+	if changed {
+		ts, err = c.SetTeamSettings(ctx, *name, p)
+	} else {
+		ts, err = c.GetTeamSettings(ctx, *name)
+	}
+	if err != nil {
+		return result{}, err
+	}
+	cursor := ""
+	if ts.RotationCursor != nil {
+		cursor = *ts.RotationCursor
+	}
+	avoid := ""
+	if ts.PairAvoidancePRs != nil {
+		avoid = strconv.Itoa(*ts.PairAvoidancePRs)
+	}
+	return result{
+		raw:    ts,
+		header: []string{"team_name", "review_sla", "escalate_after", "strategy", "rotation_cursor", "rules", "avoid_pairs", "prefer_working_hours"},
+		rows: [][]string{{ts.TeamName, formatSeconds(ts.ReviewSLASeconds), formatSeconds(ts.EscalateAfterSeconds),
+			string(ts.AssignmentStrategy), cursor, formatRules(ts.ReviewerRules), avoid,
+			strconv.FormatBool(ts.PreferWorkingHours)}},
+	}, nil
+}
+
+// parseRules reads reviewer rules written as by formatRules.
+func parseRules(s string) ([]client.ReviewerRule, error) {
+	rules := []client.ReviewerRule{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		spec, label, _ := strings.Cut(part, ":")
+		kind, value, ok := strings.Cut(spec, "=")
+		r := client.ReviewerRule{IfLabel: label}
+		switch {
+		case ok && kind == "level" && value != "":
+			r.Level = value
+		case ok && kind == "skill" && value != "":
+			r.Skill = value
+		default:
+			return nil, usagef("-rules %q: want level=L or skill=S, optionally followed by :LABEL", part)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func formatRules(rules []client.ReviewerRule) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		s := "skill=" + r.Skill
+		if r.Level != "" {
+			s = "level=" + r.Level
+		}
+		if r.IfLabel != "" {
+			s += ":" + r.IfLabel
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// formatSeconds prints an optional number of seconds as a duration.
+func formatSeconds(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return (time.Duration(*v) * time.Second).String()
+}
+
+func teamResult(t client.Team) result {
+	r := result{raw: t, header: []string{"team_name", "user_id", "username", "is_active"}}
+	for _, m := range t.Members {
+		r.rows = append(r.rows, []string{t.TeamName, m.UserID, m.Username, strconv.FormatBool(m.IsActive)})
+	}
+	return r
+}
+
+// -------- user --------
+
+func userSetActive(active bool) func(context.Context, *client.Client, *flag.FlagSet, []string) (result, error) {
+	return func(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+		id := fs.String("id", "", "user id")
+		if err := parse(fs, args, map[string]*string{"id": id}); err != nil {
+			return result{}, err
+		}
+		u, err := c.SetUserActive(ctx, *id, active)
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			raw:    u,
+			header: []string{"user_id", "username", "team_name", "is_active"},
+			rows:   [][]string{{u.UserID, u.Username, u.TeamName, strconv.FormatBool(u.IsActive)}},
+		}, nil
+	}
+}
+
+func userReviews(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	id := fs.String("id", "", "user id")
+	status := fs.String("status", "", "OPEN or MERGED")
+	all := fs.Bool("all", false, "follow cursors and print every page")
+	limit := fs.Int("limit", 0, "page size")
+	if err := parse(fs, args, map[string]*string{"id": id}); err != nil {
+		return result{}, err
+	}
+
+	list, err := collect(*all, client.Page{Limit: *limit}, func(p client.Page) ([]client.PullRequestShort, string, error) {
+		return c.GetUserReviews(ctx, *id, client.PRStatus(*status), p)
+	})
+	if err != nil {
+		return result{}, err
+	}
+	r := result{raw: list, header: []string{"pull_request_id", "pull_request_name", "author_id", "status"}}
+	for _, pr := range list {
+		r.rows = append(r.rows, []string{pr.PullRequestID, pr.PullRequestName, pr.AuthorID, string(pr.Status)})
+	}
+	return r, nil
+}
+
+func userSettings(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	id := fs.String("id", "", "user id")
+	maxOpen := fs.Int("max-open-reviews", 0, "open reviews the user can have at a time, 0 removes the limit")
+	weight := fs.Int("weight", 1, "relative chance of being picked as a reviewer, 0 for never")
+	level := fs.String("level", "", "level matched by team rules, e.g. senior; empty removes it")
+	skills := fs.String("skills", "", "comma-separated skills matched by team rules; empty removes all")
+	blockAuthors := fs.String("block-authors", "", "comma-separated authors whose PRs the user never reviews; empty removes all")
+	blockLabels := fs.String("block-labels", "", "comma-separated labels of PRs the user never reviews; empty removes all")
+	timezone := fs.String("timezone", "", "IANA time zone of the working hours, e.g. Europe/Berlin; empty means UTC")
+	hours := fs.String("hours", "", "working hours as HH:MM-HH:MM; empty removes them")
+	if err := parse(fs, args, map[string]*string{"id": id}); err != nil {
+		return result{}, err
+	}
+
+	var p client.UserSettingsPatch
+	changed, hoursSet := false, false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-open-reviews":
+			p.MaxOpenReviews, changed = maxOpen, true
+		case "weight":
+			p.ReviewWeight, changed = weight, true
+		case "level":
+			p.Level, changed = level, true
+		case "skills":
+			list := splitList(*skills)
+			p.Skills, changed = &list, true
+		case "block-authors":
+			list := splitList(*blockAuthors)
+			p.BlockedAuthors, changed = &list, true
+		case "block-labels":
+			list := splitList(*blockLabels)
+			p.BlockedLabels, changed = &list, true
+		case "timezone":
+			p.Timezone, changed = timezone, true
+		case "hours":
+			hoursSet, changed = true, true
+		}
+	})
+	if hoursSet {
+		start, end := "", ""
+		if *hours != "" {
+			var ok bool
+			if start, end, ok = strings.Cut(*hours, "-"); !ok {
+				return result{}, usagef("-hours must be HH:MM-HH:MM, got %q", *hours)
+			}
+		}
+		p.WorkStart, p.WorkEnd = &start, &end
+	}
+
+	var us client.UserSettings
+	var err error
+	if changed {
+		us, err = c.SetUserSettings(ctx, *id, p)
+	} else {
+		us, err = c.GetUserSettings(ctx, *id)
+	}
+	if err != nil {
+		return result{}, err
+	}
+	maxOpenReviews := ""
+	if us.MaxOpenReviews != nil {
+		maxOpenReviews = strconv.Itoa(*us.MaxOpenReviews)
+	}
+	userLevel := ""
+	if us.Level != nil {
+		userLevel = *us.Level
+	}
+	tz, workingHours := "", ""
+	if us.Timezone != nil {
+		tz = *us.Timezone
+	}
+	if us.WorkStart != nil && us.WorkEnd != nil {
+		workingHours = *us.WorkStart + "-" + *us.WorkEnd
+	}
+	return result{
+		raw: us,
+		header: []string{"user_id", "max_open_reviews", "review_weight", "level", "skills", "blocked_authors", "blocked_labels",
+			"timezone", "working_hours"},
+		rows: [][]string{{us.UserID, maxOpenReviews, strconv.Itoa(us.ReviewWeight), userLevel, strings.Join(us.Skills, ","),
+			strings.Join(us.BlockedAuthors, ","), strings.Join(us.BlockedLabels, ","), tz, workingHours}},
+	}, nil
+}
+
+// -------- pr --------
+
+func prCreate(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	id := fs.String("id", "", "pull request id")
+	name := fs.String("name", "", "pull request name")
+	author := fs.String("author", "", "author user id")
+	labels := fs.String("labels", "", "comma-separated labels")
+	if err := parse(fs, args, map[string]*string{"id": id, "name": name, "author": author}); err != nil {
+		return result{}, err
+	}
+	pr, shortfall, err := c.CreatePRWithShortfall(ctx, *id, *name, *author, splitList(*labels)...)
+	if err != nil {
+		return result{}, err
+	}
+	r := prResult(pr)
+	if shortfall != nil {
+		r.raw = map[string]any{"pr": pr, "capacity_shortfall": shortfall}
+		r.header = append(r.header, "at_capacity")
+		r.rows[0] = append(r.rows[0], strings.Join(shortfall.AtCapacity, " "))
+	}
+	return r, nil
+}
+
+func prMerge(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	id := fs.String("id", "", "pull request id")
+	if err := parse(fs, args, map[string]*string{"id": id}); err != nil {
+		return result{}, err
+	}
+	pr, err := c.MergePR(ctx, *id)
+	if err != nil {
+		return result{}, err
+	}
+	return prResult(pr), nil
+}
+
+func prReassign(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	id := fs.String("id", "", "pull request id")
+	old := fs.String("old", "", "reviewer to replace")
+	if err := parse(fs, args, map[string]*string{"id": id, "old": old}); err != nil {
+		return result{}, err
+	}
+	pr, by, err := c.ReassignReviewer(ctx, *id, *old)
+	if err != nil {
+		return result{}, err
+	}
+	r := prResult(pr)
+	r.raw = map[string]any{"pr": pr, "replaced_by": by}
+	r.header = append(r.header, "replaced_by")
+	r.rows[0] = append(r.rows[0], by)
+	return r, nil
+}
+
+func prReview(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	id := fs.String("id", "", "pull request id")
+	user := fs.String("user", "", "reviewer")
+	if err := parse(fs, args, map[string]*string{"id": id, "user": user}); err != nil {
+		return result{}, err
+	}
+	pr, at, err := c.SubmitReview(ctx, *id, *user)
+	if err != nil {
+		return result{}, err
+	}
+	r := prResult(pr)
+	r.raw = map[string]any{"pr": pr, "reviewed_at": at}
+	r.header = append(r.header, "reviewed_at")
+	r.rows[0] = append(r.rows[0], at.Format(time.RFC3339))
+	return r, nil
+}
+
+func prGet(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	id := fs.String("id", "", "pull request id")
+	if err := parse(fs, args, map[string]*string{"id": id}); err != nil {
+		return result{}, err
+	}
+	pr, err := c.GetPR(ctx, *id)
+	if err != nil {
+		return result{}, err
+	}
+	return prResult(pr), nil
+}
+
+func prResult(pr client.PullRequest) result {
+	merged := ""
+	if pr.MergedAt != nil {
+		merged = pr.MergedAt.Format(time.RFC3339)
+	}
+	return result{
+		raw:    pr,
+		header: []string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers", "created_at", "merged_at"},
+		rows: [][]string{{
+			pr.PullRequestID, pr.PullRequestName, pr.AuthorID, string(pr.Status),
+			strings.Join(pr.AssignedReviewers, " "), pr.CreatedAt.Format(time.RFC3339), merged,
+		}},
+	}
+}
+
+// -------- admin --------
+
+func adminImport(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	file, format := orgFileFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "show the changes without applying them")
+	if err := parse(fs, args, map[string]*string{"f": file}); err != nil {
+		return result{}, err
+	}
+	data, contentType, err := readOrgFile(*file, *format)
+	if err != nil {
+		return result{}, err
+	}
+	res, err := c.Import(ctx, data, contentType, *dryRun)
+	if err != nil {
+		return result{}, err
+	}
+	return changesResult(res, res.Changes), nil
+}
+
+func adminExport(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	file := fs.String("f", "", "snapshot file to write")
+	if err := parse(fs, args, map[string]*string{"f": file}); err != nil {
+		return result{}, err
+	}
+	f, err := os.Create(*file)
+	if err != nil {
+		return result{}, err
+	}
+	counts, err := c.Export(ctx, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(*file)
+		return result{}, err
+	}
+	return countsResult(counts, counts), nil
+}
+
+func adminRestore(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	file := fs.String("f", "", "snapshot file, - for stdin")
+	if err := parse(fs, args, map[string]*string{"f": file}); err != nil {
+		return result{}, err
+	}
+	var data []byte
+	var err error
+	if *file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return result{}, err
+	}
+	counts, err := c.RestoreSnapshot(ctx, data)
+	if err != nil {
+		return result{}, err
+	}
+	return countsResult(map[string]any{"restored": counts}, counts), nil
+}
+
+func countsResult(raw any, c client.SnapshotCounts) result {
+	return result{
+		raw:    raw,
+		header: []string{"teams", "users", "pull_requests", "reviewers", "assignments"},
+		rows: [][]string{{
+			strconv.FormatInt(c.Teams, 10), strconv.FormatInt(c.Users, 10), strconv.FormatInt(c.PullRequests, 10),
+			strconv.FormatInt(c.Reviewers, 10), strconv.FormatInt(c.Assignments, 10),
+		}},
+	}
+}
+
+func adminJobs(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	if err := parse(fs, args, nil); err != nil {
+		return result{}, err
+	}
+	jobs, err := c.ListJobs(ctx)
+	if err != nil {
+		return result{}, err
+	}
+	r := result{raw: jobs, header: []string{"name", "interval", "last_status", "last_started_at", "next_run_at"}}
+	for _, j := range jobs.Jobs {
+		interval, status, started, next := "manual", "", "", ""
+		if j.IntervalSeconds > 0 {
+			interval = formatSeconds(&j.IntervalSeconds)
+		}
+		if j.LastRun != nil {
+			status, started = j.LastRun.Status, j.LastRun.StartedAt.Format(time.RFC3339)
+		}
+		if j.NextRunAt != nil {
+			next = j.NextRunAt.Format(time.RFC3339)
+		}
+		r.rows = append(r.rows, []string{j.Name, interval, status, started, next})
+	}
+	return r, nil
+}
+
+func adminJobRuns(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	name := fs.String("name", "", "only runs of this job")
+	limit := fs.Int("limit", 0, "number of runs")
+	if err := parse(fs, args, nil); err != nil {
+		return result{}, err
+	}
+	runs, err := c.JobRuns(ctx, *name, *limit)
+	if err != nil {
+		return result{}, err
+	}
+	return jobRunsResult(runs, runs), nil
+}
+
+func adminTrigger(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	name := fs.String("name", "", "job name")
+	if err := parse(fs, args, map[string]*string{"name": name}); err != nil {
+		return result{}, err
+	}
+	run, err := c.TriggerJob(ctx, *name)
+	if err != nil {
+		return result{}, err
+	}
+	return jobRunsResult(run, []client.JobRun{run}), nil
+}
+
+func jobRunsResult(raw any, runs []client.JobRun) result {
+	r := result{raw: raw, header: []string{"id", "job", "trigger", "instance", "status", "started_at", "finished_at", "error"}}
+	for _, run := range runs {
+		finished, errMsg := "", ""
+		if run.FinishedAt != nil {
+			finished = run.FinishedAt.Format(time.RFC3339)
+		}
+		if run.Error != nil {
+			errMsg = *run.Error
+		}
+		r.rows = append(r.rows, []string{
+			strconv.FormatInt(run.ID, 10), run.Job, run.Trigger, run.Instance, run.Status,
+			run.StartedAt.Format(time.RFC3339), finished, errMsg,
+		})
+	}
+	return r
+}
+
+func orgFileFlags(fs *flag.FlagSet) (file, format *string) {
+	file = fs.String("f", "", "org definition file, - for stdin")
+	format = fs.String("format", "", "yaml, json or csv (default: from the file extension, else yaml)")
+	return file, format
+}
+
+// readOrgFile reads an org definition and picks its content type.
+func readOrgFile(file, format string) ([]byte, string, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+	switch format {
+	case "csv":
+		return data, "text/csv", nil
+	case "json":
+		return data, "application/json", nil
+	case "", "yaml", "yml":
+		return data, "application/yaml", nil
+	default:
+		return nil, "", usagef("-format %q: want yaml, json or csv", format)
+	}
+}
+
+func changesResult(raw any, changes []client.ImportChange) result {
+	r := result{raw: raw, header: []string{"action", "team_name", "user_id", "from_team", "fields"}}
+	for _, ch := range changes {
+		r.rows = append(r.rows, []string{ch.Action, ch.TeamName, ch.UserID, ch.FromTeam, strings.Join(ch.Fields, " ")})
+	}
+	return r
+}
+
+// -------- stats --------
+
+func statsUsers(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	bucket := fs.String("bucket", "", "print a time series with day, week or month buckets")
+	all, p, err := statsFlags(fs, args)
+	if err != nil {
+		return result{}, err
+	}
+	if *bucket != "" {
+		list, err := collect(all, p, func(p client.Page) ([]client.UserSeries, string, error) {
+			return c.UserSeries(ctx, *bucket, p)
+		})
+		if err != nil {
+			return result{}, err
+		}
+		r := result{raw: list, header: []string{"user_id", "start", "count"}}
+		for _, s := range list {
+			r.rows = append(r.rows, seriesRows(s.UserID, s.Points)...)
+		}
+		return r, nil
+	}
+	list, err := collect(all, p, func(p client.Page) ([]client.UserAssignStat, string, error) {
+		return c.UserStats(ctx, p)
+	})
+	if err != nil {
+		return result{}, err
+	}
+	r := result{raw: list, header: []string{"user_id", "count"}}
+	for _, s := range list {
+		r.rows = append(r.rows, []string{s.UserID, strconv.FormatInt(s.Count, 10)})
+	}
+	return r, nil
+}
+
+func statsPRs(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	all, p, err := statsFlags(fs, args)
+	if err != nil {
+		return result{}, err
+	}
+	list, err := collect(all, p, func(p client.Page) ([]client.PRAssignStat, string, error) {
+		return c.PRStats(ctx, p)
+	})
+	if err != nil {
+		return result{}, err
+	}
+	r := result{raw: list, header: []string{"pull_request_id", "count"}}
+	for _, s := range list {
+		r.rows = append(r.rows, []string{s.PullRequestID, strconv.FormatInt(s.Count, 10)})
+	}
+	return r, nil
+}
+
+func statsTeams(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	bucket := fs.String("bucket", "", "print a time series with day, week or month buckets")
+	all, p, err := statsFlags(fs, args)
+	if err != nil {
+		return result{}, err
+	}
+	if *bucket == "" {
+		list, err := collect(all, p, func(p client.Page) ([]client.TeamAssignStat, string, error) {
+			return c.TeamStats(ctx, p)
+		})
+		if err != nil {
+			return result{}, err
+		}
+		r := result{raw: list, header: []string{"team_name", "count"}}
+		for _, s := range list {
+			r.rows = append(r.rows, []string{s.TeamName, strconv.FormatInt(s.Count, 10)})
+		}
+		return r, nil
+	}
+	list, err := collect(all, p, func(p client.Page) ([]client.TeamSeries, string, error) {
+		return c.TeamSeries(ctx, *bucket, p)
+	})
+	if err != nil {
+		return result{}, err
+	}
+	r := result{raw: list, header: []string{"team_name", "start", "count"}}
+	for _, s := range list {
+		r.rows = append(r.rows, seriesRows(s.TeamName, s.Points)...)
+	}
+	return r, nil
+}
+
+func statsFairness(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	team := fs.String("team", "", "only this team")
+	from := fs.String("from", "", "only assignments at or after, RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "only assignments before, RFC 3339 or YYYY-MM-DD")
+	if err := parse(fs, args, nil); err != nil {
+		return result{}, err
+	}
+	fromT, err := parseTime("from", *from)
+	if err != nil {
+		return result{}, err
+	}
+	toT, err := parseTime("to", *to)
+	if err != nil {
+		return result{}, err
+	}
+	list, err := c.Fairness(ctx, *team, fromT, toT)
+	if err != nil {
+		return result{}, err
+	}
+	r := result{raw: list, header: []string{"team_name", "active_members", "assignments", "mean", "stddev", "gini", "min", "max", "max_min_ratio"}}
+	for _, f := range list {
+		ratio := ""
+		if f.MaxMinRatio != nil {
+			ratio = formatFloat(*f.MaxMinRatio)
+		}
+		r.rows = append(r.rows, []string{
+			f.TeamName, strconv.Itoa(f.ActiveMembers), strconv.FormatInt(f.Assignments, 10),
+			formatFloat(f.Mean), formatFloat(f.StdDev), formatFloat(f.Gini),
+			strconv.FormatInt(f.Min, 10), strconv.FormatInt(f.Max, 10), ratio,
+		})
+	}
+	return r, nil
+}
+
+func statsLatency(ctx context.Context, c *client.Client, fs *flag.FlagSet, args []string) (result, error) {
+	by := fs.String("by", "teams", "teams or reviewers")
+	team := fs.String("team", "", "only this team")
+	from := fs.String("from", "", "only PRs created (assignments made) at or after, RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "only PRs created (assignments made) before, RFC 3339 or YYYY-MM-DD")
+	if err := parse(fs, args, nil); err != nil {
+		return result{}, err
+	}
+	fromT, err := parseTime("from", *from)
+	if err != nil {
+		return result{}, err
+	}
+	toT, err := parseTime("to", *to)
+	if err != nil {
+		return result{}, err
+	}
+
+	switch *by {
+	case "teams":
+		list, err := c.TeamLatency(ctx, *team, fromT, toT)
+		if err != nil {
+			return result{}, err
+		}
+		r := result{raw: list, header: append(append([]string{"team_name", "prs"},
+			latencyHeader("first_review")...), latencyHeader("merge")...)}
+		for _, l := range list {
+			row := append([]string{l.TeamName, strconv.FormatInt(l.PRs, 10)}, latencyRow(l.TimeToFirstReview)...)
+			r.rows = append(r.rows, append(row, latencyRow(l.TimeToMerge)...))
+		}
+		return r, nil
+	case "reviewers":
+		list, err := c.ReviewerLatency(ctx, *team, fromT, toT)
+		if err != nil {
+			return result{}, err
+		}
+		r := result{raw: list, header: append([]string{"user_id", "team_name", "assigned", "pending"}, latencyHeader("review")...)}
+		for _, l := range list {
+			row := []string{l.UserID, l.TeamName, strconv.FormatInt(l.Assigned, 10), strconv.FormatInt(l.Pending, 10)}
+			r.rows = append(r.rows, append(row, latencyRow(l.TimeToReview)...))
+		}
+		return r, nil
+	default:
+		return result{}, usagef("-by %q: want teams or reviewers", *by)
+	}
+}
+
+func latencyHeader(prefix string) []string {
+	return []string{prefix + "_count", prefix + "_p50", prefix + "_p90", prefix + "_p95"}
+}
+
+// latencyRow prints the percentiles as durations rounded to seconds.
+func latencyRow(l client.Latency) []string {
+	row := []string{strconv.FormatInt(l.Count, 10)}
+	for _, p := range []*float64{l.P50, l.P90, l.P95} {
+		v := ""
+		if p != nil {
+			v = time.Duration(*p * float64(time.Second)).Round(time.Second).String()
+		}
+		row = append(row, v)
+	}
+	return row
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// seriesRows flattens a time series into one row per bucket.
+func seriesRows(key string, points []client.StatsPoint) [][]string {
+	rows := make([][]string, 0, len(points))
+	for _, pt := range points {
+		rows = append(rows, []string{key, pt.Start.Format(time.RFC3339), strconv.FormatInt(pt.Count, 10)})
+	}
+	return rows
+}
+
+func statsFlags(fs *flag.FlagSet, args []string) (bool, client.Page, error) {
+	var p client.Page
+	fs.IntVar(&p.Limit, "limit", 0, "page size")
+	fs.StringVar(&p.Sort, "sort", "", "count or id")
+	fs.StringVar(&p.Order, "order", "", "asc or desc")
+	from := fs.String("from", "", "only assignments at or after, RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "only assignments before, RFC 3339 or YYYY-MM-DD")
+	all := fs.Bool("all", false, "follow cursors and print every page")
+	if err := parse(fs, args, nil); err != nil {
+		return false, p, err
+	}
+	var err error
+	if p.From, err = parseTime("from", *from); err != nil {
+		return false, p, err
+	}
+	if p.To, err = parseTime("to", *to); err != nil {
+		return false, p, err
+	}
+	return *all, p, nil
+}
+
+func parseTime(name, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, v)
+	}
+	if err != nil {
+		return time.Time{}, usagef("-%s: want an RFC 3339 timestamp or YYYY-MM-DD date", name)
+	}
+	return t, nil
+}
+
+// collect fetches one page, or every page when all is set.
+func collect[T any](all bool, p client.Page, fetch func(client.Page) ([]T, string, error)) ([]T, error) {
+	res := []T{}
+	for {
+		list, next, err := fetch(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, list...)
+		if !all || next == "" {
+			return res, nil
+		}
+		p.Cursor = next
+	}
+}
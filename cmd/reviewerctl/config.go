@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// profile is one named entry of the config file:
+//
+//	current: prod
+//	profiles:
+//	  prod:
+//	    url: https://reviewer.internal
+//	    output: table
+//	    timeout: 10s
+type profile struct {
+	URL     string        `yaml:"url"`
+	Output  string        `yaml:"output"`
+	Timeout time.Duration `yaml:"timeout"`
+	Retries *int          `yaml:"retries"`
+}
+
+type configFile struct {
+	Current  string             `yaml:"current"`
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+// settings are the effective connection settings after merging flags, env
+// and the selected profile, in that order of precedence.
+type settings struct {
+	URL     string
+	Output  string
+	Timeout time.Duration
+	Retries int
+}
+
+func defaultConfigPath() string {
+	if p := os.Getenv("REVIEWERCTL_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "reviewerctl", "config.yaml")
+}
+
+func loadConfig(path string) (configFile, error) {
+	var cf configFile
+	if path == "" {
+		return cf, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cf, nil
+	}
+	if err != nil {
+		return cf, err
+	}
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		return cf, fmt.Errorf("%s: %w", path, err)
+	}
+	return cf, nil
+}
+
+func resolveSettings(g globalFlags) (settings, error) {
+	cf, err := loadConfig(g.config)
+	if err != nil {
+		return settings{}, err
+	}
+
+	name := firstNonEmpty(g.profile, os.Getenv("REVIEWERCTL_PROFILE"), cf.Current)
+	var p profile
+	if name != "" {
+		var ok bool
+		if p, ok = cf.Profiles[name]; !ok {
+			return settings{}, fmt.Errorf("profile %q not found in %s", name, g.config)
+		}
+	}
+
+	s := settings{
+		URL:     firstNonEmpty(g.url, os.Getenv("REVIEWERCTL_URL"), p.URL, defaultURL),
+		Output:  firstNonEmpty(g.output, os.Getenv("REVIEWERCTL_OUTPUT"), p.Output, "table"),
+		Timeout: 10 * time.Second,
+		Retries: 3,
+	}
+	if p.Timeout > 0 {
+		s.Timeout = p.Timeout
+	}
+	if g.timeout > 0 {
+		s.Timeout = g.timeout
+	}
+	if p.Retries != nil {
+		s.Retries = *p.Retries
+	}
+	switch s.Output {
+	case "table", "json", "csv":
+	default:
+		return settings{}, fmt.Errorf("unknown output format %q (want table, json or csv)", s.Output)
+	}
+	return s, nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	return path
+}
+
+const testConfig = `
+current: staging
+profiles:
+  staging:
+    url: http://staging:8080
+    output: csv
+    timeout: 3s
+    retries: 0
+  prod:
+    url: http://prod:8080
+    output: json
+`
+
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, k := range []string{"REVIEWERCTL_URL", "REVIEWERCTL_PROFILE", "REVIEWERCTL_OUTPUT"} {
+		t.Setenv(k, "")
+	}
+}
+
+func TestConfig_DefaultsWithoutConfigFile(t *testing.T) {
+	clearEnv(t)
+	s, err := resolveSettings(globalFlags{config: filepath.Join(t.TempDir(), "missing.yaml")})
+	require.NoError(t, err)
+	require.Equal(t, settings{URL: defaultURL, Output: "table", Timeout: 10 * time.Second, Retries: 3}, s)
+}
+
+func TestConfig_CurrentProfile(t *testing.T) {
+	clearEnv(t)
+	s, err := resolveSettings(globalFlags{config: writeConfig(t, testConfig)})
+	require.NoError(t, err)
+	require.Equal(t, settings{URL: "http://staging:8080", Output: "csv", Timeout: 3 * time.Second, Retries: 0}, s)
+}
+
+func TestConfig_FlagsOverrideEnvOverrideProfile(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, testConfig)
+
+	t.Setenv("REVIEWERCTL_PROFILE", "prod")
+	s, err := resolveSettings(globalFlags{config: path})
+	require.NoError(t, err)
+	require.Equal(t, "http://prod:8080", s.URL)
+	require.Equal(t, "json", s.Output)
+
+	t.Setenv("REVIEWERCTL_URL", "http://env:8080")
+	t.Setenv("REVIEWERCTL_OUTPUT", "table")
+	s, err = resolveSettings(globalFlags{config: path})
+	require.NoError(t, err)
+	require.Equal(t, "http://env:8080", s.URL)
+	require.Equal(t, "table", s.Output)
+
+	s, err = resolveSettings(globalFlags{config: path, profile: "staging", url: "http://flag:8080", output: "csv", timeout: time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, settings{URL: "http://flag:8080", Output: "csv", Timeout: time.Minute, Retries: 0}, s)
+}
+
+func TestConfig_Errors(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, testConfig)
+
+	_, err := resolveSettings(globalFlags{config: path, profile: "dev"})
+	require.ErrorContains(t, err, `profile "dev" not found`)
+
+	_, err = resolveSettings(globalFlags{config: path, output: "xml"})
+	require.ErrorContains(t, err, `unknown output format "xml"`)
+
+	_, err = resolveSettings(globalFlags{config: writeConfig(t, "profiles: [")})
+	require.Error(t, err)
+}
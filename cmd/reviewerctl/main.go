@@ -0,0 +1,140 @@
+// Command reviewerctl is an admin CLI for the reviewer-service HTTP API.
+//
+//	reviewerctl [global flags] <group> <command> [flags]
+//
+// The server URL comes from -url, REVIEWERCTL_URL or the selected profile of
+// the config file (see config.go), falling back to http://localhost:8080.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"reviewer-service/client"
+)
+
+type globalFlags struct {
+	url     string
+	profile string
+	config  string
+	output  string
+	timeout time.Duration
+}
+
+// usageError reports bad command arguments; it exits with status 2.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+func usagef(format string, args ...any) error {
+	return usageError(fmt.Sprintf(format, args...))
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var g globalFlags
+	fs := flag.NewFlagSet("reviewerctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&g.url, "url", "", "API base URL (env REVIEWERCTL_URL)")
+	fs.StringVar(&g.profile, "profile", "", "profile from the config file (env REVIEWERCTL_PROFILE)")
+	fs.StringVar(&g.config, "config", defaultConfigPath(), "config file (env REVIEWERCTL_CONFIG)")
+	fs.StringVar(&g.output, "o", "", "output format: table, json or csv (env REVIEWERCTL_OUTPUT)")
+	fs.DurationVar(&g.timeout, "timeout", 0, "request timeout, including retries (default 10s)")
+	fs.Usage = func() { printUsage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	rest := fs.Args()
+	if len(rest) < 2 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[rest[0]][rest[1]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", strings.Join(rest[:2], " "))
+		fs.Usage()
+		return 2
+	}
+
+	s, err := resolveSettings(g)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 2
+	}
+	c, err := client.New(s.URL, client.WithRetries(s.Retries), client.WithUserAgent("reviewerctl"))
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	cmdFlags := flag.NewFlagSet(rest[0]+" "+rest[1], flag.ContinueOnError)
+	cmdFlags.SetOutput(stderr)
+	res, err := cmd.run(ctx, c, cmdFlags, rest[2:])
+	if err != nil {
+		return reportErr(stderr, cmdFlags, err)
+	}
+	if err := render(stdout, s.Output, res); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func reportErr(w io.Writer, fs *flag.FlagSet, err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	var ue usageError
+	if errors.As(err, &ue) {
+		fmt.Fprintln(w, "error:", err)
+		fs.Usage()
+		return 2
+	}
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		fmt.Fprintf(w, "error: %s: %s\n", apiErr.Code, apiErr.Message)
+		for _, f := range apiErr.Details {
+			fmt.Fprintf(w, "  %s: %s\n", f.Field, f.Reason)
+		}
+		return 1
+	}
+	fmt.Fprintln(w, "error:", err)
+	return 1
+}
+
+func printUsage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "usage: reviewerctl [global flags] <group> <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	groups := make([]string, 0, len(commands))
+	for g := range commands {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		names := make([]string, 0, len(commands[g]))
+		for n := range commands[g] {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintf(w, "  %-24s %s\n", g+" "+n, commands[g][n].summary)
+		}
+	}
+	fmt.Fprintln(w, "\nglobal flags:")
+	fs.PrintDefaults()
+}
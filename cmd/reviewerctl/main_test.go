@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pull_request_id") != "pr1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"NOT_FOUND","message":"resource not found"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"pr":{"pull_request_id":"pr1","pull_request_name":"Add search","author_id":"u1",
+			"status":"OPEN","assigned_reviewers":["u2","u3"],"createdAt":"2025-01-10T12:00:00Z"}}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	clearEnv(t)
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-config", filepath.Join(t.TempDir(), "none.yaml")}, args...)
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_RendersCommandResult(t *testing.T) {
+	ts := newTestAPI(t)
+
+	code, out, _ := runCLI(t, "-url", ts.URL, "-o", "csv", "pr", "get", "-id", "pr1")
+	require.Equal(t, 0, code)
+	require.Equal(t, "pull_request_id,pull_request_name,author_id,status,reviewers,created_at,merged_at\n"+
+		"pr1,Add search,u1,OPEN,u2 u3,2025-01-10T12:00:00Z,\n", out)
+
+	code, out, _ = runCLI(t, "-url", ts.URL, "-o", "json", "pr", "get", "-id", "pr1")
+	require.Equal(t, 0, code)
+	require.Contains(t, out, `"assigned_reviewers": [`)
+}
+
+func TestRun_APIErrorExitsWithOne(t *testing.T) {
+	ts := newTestAPI(t)
+	code, out, errOut := runCLI(t, "-url", ts.URL, "pr", "get", "-id", "nope")
+	require.Equal(t, 1, code)
+	require.Empty(t, out)
+	require.Equal(t, "error: NOT_FOUND: resource not found\n", errOut)
+}
+
+func TestRun_UsageErrorsExitWithTwo(t *testing.T) {
+	ts := newTestAPI(t)
+	for name, args := range map[string][]string{
+		"no command":      {},
+		"unknown command": {"pr", "close"},
+		"missing flag":    {"pr", "get"},
+		"extra argument":  {"pr", "get", "-id", "pr1", "pr2"},
+		"unknown flag":    {"pr", "get", "-nope"},
+		"bad output":      {"-o", "xml", "pr", "get", "-id", "pr1"},
+	} {
+		code, out, errOut := runCLI(t, append([]string{"-url", ts.URL}, args...)...)
+		require.Equal(t, 2, code, name)
+		require.Empty(t, out, name)
+		require.NotEmpty(t, errOut, name)
+	}
+	_, _, errOut := runCLI(t, "-url", ts.URL, "pr", "get")
+	require.Contains(t, errOut, "error: -id required")
+}
+
+func TestParse_ReportsMissingFlagsInOrder(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	a, b := fs.String("a", "", ""), fs.String("b", "", "")
+	err := parse(fs, nil, map[string]*string{"b": b, "a": a})
+	require.EqualError(t, err, "-a, -b required")
+	var ue usageError
+	require.ErrorAs(t, err, &ue)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// result is what a command prints: raw is emitted as-is for -o json, header
+// and rows are used for the table and csv formats.
+type result struct {
+	raw    any
+	header []string
+	rows   [][]string
+}
+
+func render(w io.Writer, format string, r result) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.raw)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(r.header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(r.rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(r.header, "\t"))
+		for _, row := range r.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testResult = result{
+	raw:    map[string]any{"team_name": "backend", "members": 2},
+	header: []string{"user_id", "username"},
+	rows:   [][]string{{"u1", "Alice"}, {"u22", "Bob, Jr."}},
+}
+
+func TestRender_Table(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, render(&buf, "table", testResult))
+	require.Equal(t, "user_id  username\nu1       Alice\nu22      Bob, Jr.\n", buf.String())
+}
+
+func TestRender_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, render(&buf, "json", testResult))
+	require.JSONEq(t, `{"team_name":"backend","members":2}`, buf.String())
+	require.Contains(t, buf.String(), "\n  ")
+}
+
+func TestRender_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, render(&buf, "csv", testResult))
+	require.Equal(t, "user_id,username\nu1,Alice\nu22,\"Bob, Jr.\"\n", buf.String())
+}
@@ -3,16 +3,22 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// The runtime image has no zoneinfo; users' timezones need the embedded copy.
+	_ "time/tzdata"
 
 	"reviewer-service/internal/config"
 	"reviewer-service/internal/db"
+	graphqlx "reviewer-service/internal/graphql"
+	grpcx "reviewer-service/internal/grpc"
 	httpx "reviewer-service/internal/http"
 	"reviewer-service/internal/repo"
+	"reviewer-service/internal/scheduler"
 	"reviewer-service/internal/service"
 )
 
@@ -28,7 +34,26 @@ func main() {
 	rp := repo.New(pool)
 	svc := service.New(rp)
 
-	router := httpx.NewRouter(svc)
+	spec, err := httpx.LoadSpec(context.Background(), cfg.OpenAPISpec)
+	if err != nil {
+		log.Fatalf("load openapi spec %s: %v", cfg.OpenAPISpec, err)
+	}
+
+	jobs := scheduler.New(rp)
+	jobs.Register(scheduler.StaleReviews(svc, cfg.ReminderInterval))
+	jobs.Register(scheduler.IdempotencyPurge(svc, cfg.IdempotencyPurgeInterval))
+
+	router, err := httpx.NewRouter(svc, httpx.Options{
+		IdempotencyTTL:    cfg.IdempotencyTTL,
+		Spec:              spec,
+		ValidateResponses: cfg.ValidateResponses,
+		GraphQL:           graphqlx.NewHandler(rp),
+		SCIMToken:         cfg.SCIMToken,
+		Jobs:              jobs,
+	})
+	if err != nil {
+		log.Fatalf("router: %v", err)
+	}
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -45,12 +70,35 @@ func main() {
 		}
 	}()
 
+	bg, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	jobsDone := make(chan struct{})
+	go func() {
+		jobs.Run(bg)
+		close(jobsDone)
+	}()
+
+	grpcSrv := grpcx.NewServer(svc)
+	lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		log.Fatalf("grpc listen: %v", err)
+	}
+	go func() {
+		log.Printf("grpc listening on %s", lis.Addr())
+		if err := grpcSrv.Serve(lis); err != nil {
+			log.Fatalf("grpc serve: %v", err)
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
+	stopBackground()
+	<-jobsDone
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_ = srv.Shutdown(ctx)
+	grpcSrv.GracefulStop()
 	log.Println("shutdown complete")
 }
@@ -1,16 +1,46 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 type Config struct {
 	Port        string
+	GRPCPort    string
 	DatabaseURL string
+
+	IdempotencyTTL time.Duration
+	// IdempotencyPurgeInterval is how often expired idempotency keys are
+	// deleted; with 0 only when the job is triggered by hand.
+	IdempotencyPurgeInterval time.Duration
+
+	OpenAPISpec       string
+	ValidateResponses bool
+
+	SCIMToken string
+
+	// ReminderInterval is how often stale reviews are checked; with 0 they
+	// are only checked when the job is triggered by hand.
+	ReminderInterval time.Duration
 }
 
 func FromEnv() Config {
 	return Config{
 		Port:        getenv("PORT", "8080"),
+		GRPCPort:    getenv("GRPC_PORT", "9090"),
 		DatabaseURL: getenv("DATABASE_URL", "postgres://postgres:postgres@db:5432/reviewer?sslmode=disable"),
+
+		IdempotencyTTL:           getduration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyPurgeInterval: getduration("IDEMPOTENCY_PURGE_INTERVAL", time.Hour),
+
+		OpenAPISpec:       getenv("OPENAPI_SPEC", "openapi.yml"),
+		ValidateResponses: getbool("OPENAPI_VALIDATE_RESPONSES", false),
+
+		SCIMToken: os.Getenv("SCIM_TOKEN"),
+
+		ReminderInterval: getduration("REMINDER_INTERVAL", time.Minute),
 	}
 }
 
@@ -20,3 +50,21 @@ func getenv(k, def string) string {
 	}
 	return def
 }
+
+func getduration(k string, def time.Duration) time.Duration {
+	if v := os.Getenv(k); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+func getbool(k string, def bool) bool {
+	if v := os.Getenv(k); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
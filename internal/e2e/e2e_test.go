@@ -4,17 +4,37 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
 
 	"reviewer-service/internal/config"
 	"reviewer-service/internal/db"
+	graphqlx "reviewer-service/internal/graphql"
+	grpcx "reviewer-service/internal/grpc"
+	"reviewer-service/internal/grpc/reviewerv1"
 	httpx "reviewer-service/internal/http"
+	"reviewer-service/internal/models"
 	"reviewer-service/internal/repo"
+	"reviewer-service/internal/scheduler"
 	"reviewer-service/internal/service"
 )
 
@@ -26,9 +46,18 @@ func newTestServer(t *testing.T) *httptest.Server {
 	pool, err := db.NewPool(context.Background(), cfg.DatabaseURL)
 	require.NoError(t, err)
 
+	spec, err := httpx.LoadSpec(context.Background(), "../../openapi.yml")
+	require.NoError(t, err)
+
 	rp := repo.New(pool)
 	svc := service.New(rp)
-	handler := httpx.NewRouter(svc)
+	handler, err := httpx.NewRouter(svc, httpx.Options{
+		IdempotencyTTL:    cfg.IdempotencyTTL,
+		Spec:              spec,
+		ValidateResponses: true,
+		GraphQL:           graphqlx.NewHandler(rp),
+	})
+	require.NoError(t, err)
 	return httptest.NewServer(handler)
 }
 
@@ -100,6 +129,1565 @@ func TestE2E_Deactivate_SafeReassign(t *testing.T) {
 	}, 200, &pr)
 }
 
+func TestE2E_IdempotencyKey_ReplaysFirstResponse(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "idem",
+		"members": []map[string]any{
+			{"user_id": "i1", "username": "I1", "is_active": true},
+			{"user_id": "i2", "username": "I2", "is_active": true},
+		},
+	}, 201, nil)
+
+	body := map[string]any{
+		"pull_request_id":   "pr-idem",
+		"pull_request_name": "Idempotent PR",
+		"author_id":         "i1",
+	}
+	var first, second map[string]any
+	doWithKey(t, ts, "/pullRequest/create", "key-pr-idem", body, 201, &first)
+	h := doWithKey(t, ts, "/pullRequest/create", "key-pr-idem", body, 201, &second)
+	require.Equal(t, "true", h.Get("Idempotency-Replayed"))
+	require.Equal(t, first, second)
+
+	body["pull_request_name"] = "Other name"
+	doWithKey(t, ts, "/pullRequest/create", "key-pr-idem", body, 422, nil)
+}
+
+func TestE2E_PRList_FiltersAndPaginates(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "search",
+		"members": []map[string]any{
+			{"user_id": "s1", "username": "S1", "is_active": true},
+			{"user_id": "s2", "username": "S2", "is_active": true},
+		},
+	}, 201, nil)
+	for _, id := range []string{"pr-s1", "pr-s2", "pr-s3"} {
+		do(t, ts, "POST", "/pullRequest/create", map[string]any{
+			"pull_request_id":   id,
+			"pull_request_name": "Search " + id,
+			"author_id":         "s1",
+			"labels":            []string{"search-" + id},
+		}, 201, nil)
+	}
+
+	var page struct {
+		PRs []struct {
+			ID     string   `json:"pull_request_id"`
+			Labels []string `json:"labels"`
+		} `json:"pull_requests"`
+		Next *string `json:"next_cursor"`
+	}
+	do(t, ts, "GET", "/pullRequest/list?team_name=search&q=search%20pr-s&limit=2", nil, 200, &page)
+	require.Len(t, page.PRs, 2)
+	require.NotNil(t, page.Next)
+
+	seen := page.PRs[0].ID + page.PRs[1].ID
+	do(t, ts, "GET", "/pullRequest/list?team_name=search&q=search%20pr-s&limit=2&cursor="+*page.Next, nil, 200, &page)
+	require.Len(t, page.PRs, 1)
+	require.Nil(t, page.Next)
+	require.NotContains(t, seen, page.PRs[0].ID)
+	require.Equal(t, []string{"search-" + page.PRs[0].ID}, page.PRs[0].Labels)
+
+	var gql struct {
+		Data struct {
+			PullRequest struct {
+				Labels []string `json:"labels"`
+			} `json:"pullRequest"`
+		} `json:"data"`
+	}
+	do(t, ts, "POST", "/graphql", map[string]any{"query": `{ pullRequest(id: "pr-s1") { labels } }`}, 200, &gql)
+	require.Equal(t, []string{"search-pr-s1"}, gql.Data.PullRequest.Labels)
+
+	do(t, ts, "GET", "/pullRequest/get?pull_request_id=pr-s1", nil, 200, nil)
+	do(t, ts, "GET", "/pullRequest/get?pull_request_id=missing", nil, 404, nil)
+}
+
+func TestE2E_Validation_ReportsFieldErrors(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	var resp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details []struct {
+				Field string `json:"field"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "invalid",
+		"members": []map[string]any{
+			{"user_id": "v1", "is_active": true},
+		},
+	}, 400, &resp)
+	require.Equal(t, "VALIDATION_ERROR", resp.Error.Code)
+	require.Len(t, resp.Error.Details, 1)
+	require.Equal(t, "members[0].username", resp.Error.Details[0].Field)
+
+	var added struct {
+		Team models.Team `json:"team"`
+	}
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "default-active",
+		"members":   []map[string]any{{"user_id": "da1", "username": "Da1"}},
+	}, 201, &added)
+	require.Equal(t, []models.TeamMember{{UserID: "da1", Username: "Da1", IsActive: true}}, added.Team.Members)
+}
+
+func TestE2E_GraphQL_TeamReviewsInOneQuery(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "dash",
+		"members": []map[string]any{
+			{"user_id": "d1", "username": "D1", "is_active": true},
+			{"user_id": "d2", "username": "D2", "is_active": true},
+			{"user_id": "d3", "username": "D3", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id":   "pr-dash",
+		"pull_request_name": "Dashboard PR",
+		"author_id":         "d1",
+	}, 201, nil)
+
+	var resp struct {
+		Data struct {
+			Team struct {
+				Members []struct {
+					ID      string `json:"id"`
+					Reviews []struct {
+						ID     string `json:"id"`
+						Author struct {
+							Username string `json:"username"`
+						} `json:"author"`
+					} `json:"reviews"`
+				} `json:"members"`
+			} `json:"team"`
+		} `json:"data"`
+		Errors []any `json:"errors"`
+	}
+	do(t, ts, "POST", "/graphql", map[string]any{
+		"query": `query($team: String!) {
+			team(name: $team) { members { id reviews(status: OPEN) { id author { username } } } }
+		}`,
+		"variables": map[string]any{"team": "dash"},
+	}, 200, &resp)
+	require.Empty(t, resp.Errors)
+	require.Len(t, resp.Data.Team.Members, 3)
+
+	reviews := 0
+	for _, m := range resp.Data.Team.Members {
+		for _, pr := range m.Reviews {
+			require.NotEqual(t, "d1", m.ID)
+			require.Equal(t, "pr-dash", pr.ID)
+			require.Equal(t, "D1", pr.Author.Username)
+			reviews++
+		}
+	}
+	require.Equal(t, 2, reviews)
+
+	var bad struct {
+		Errors []struct {
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	do(t, ts, "POST", "/graphql", map[string]any{"query": `{ pullRequests(first: 0) { id } }`}, 200, &bad)
+	require.Len(t, bad.Errors, 1)
+	require.Equal(t, "VALIDATION_ERROR", bad.Errors[0].Extensions.Code)
+}
+
+func TestE2E_AdminImport_DryRunThenApply(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "imp-old",
+		"members": []map[string]any{
+			{"user_id": "i1", "username": "I1", "is_active": true},
+			{"user_id": "i2", "username": "I2", "is_active": true},
+		},
+	}, 201, nil)
+
+	csv := "team_name,user_id,username,is_active\n" +
+		"imp-new,i1,I1,true\n" +
+		"imp-new,i3,I3,\n" +
+		"imp-old,i2,I2 renamed,false\n"
+
+	type result struct {
+		DryRun  bool `json:"dry_run"`
+		Summary struct {
+			TeamsCreated     int `json:"teams_created"`
+			UsersCreated     int `json:"users_created"`
+			UsersUpdated     int `json:"users_updated"`
+			UsersMoved       int `json:"users_moved"`
+			UsersDeactivated int `json:"users_deactivated"`
+		} `json:"summary"`
+	}
+	post := func(query string) result {
+		req, err := http.NewRequest("POST", ts.URL+"/admin/import"+query, strings.NewReader(csv))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "text/csv")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, 200, resp.StatusCode)
+		var res result
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res
+	}
+
+	plan := post("?dry_run=true")
+	require.True(t, plan.DryRun)
+	require.Equal(t, 1, plan.Summary.TeamsCreated)
+	require.Equal(t, 1, plan.Summary.UsersCreated)
+	require.Equal(t, 1, plan.Summary.UsersMoved)
+	require.Equal(t, 1, plan.Summary.UsersUpdated)
+	require.Equal(t, 1, plan.Summary.UsersDeactivated)
+	do(t, ts, "GET", "/team/get?team_name=imp-new", nil, 404, nil)
+
+	applied := post("")
+	require.False(t, applied.DryRun)
+	require.Equal(t, plan.Summary, applied.Summary)
+
+	var team struct {
+		Members []struct {
+			UserID string `json:"user_id"`
+		} `json:"members"`
+	}
+	do(t, ts, "GET", "/team/get?team_name=imp-new", nil, 200, &team)
+	require.Len(t, team.Members, 2)
+
+	again := post("")
+	require.Zero(t, again.Summary.UsersCreated+again.Summary.UsersMoved+again.Summary.UsersUpdated+again.Summary.UsersDeactivated)
+}
+
+func TestE2E_TeamSync_DeactivatesRemovedMembers(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "sync-a",
+		"members": []map[string]any{
+			{"user_id": "s1", "username": "S1", "is_active": true},
+			{"user_id": "s2", "username": "S2", "is_active": true},
+			{"user_id": "s3", "username": "S3", "is_active": true},
+			{"user_id": "s4", "username": "S4", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "sync-pr", "pull_request_name": "x", "author_id": "s1",
+	}, 201, nil)
+
+	desired := map[string]any{"teams": []map[string]any{{
+		"team_name": "sync-a",
+		"members": []map[string]any{
+			{"user_id": "s1", "username": "S1"},
+			{"user_id": "s2", "username": "S2"},
+		},
+	}}}
+	type result struct {
+		Mode    string `json:"mode"`
+		Changes []struct {
+			Action string `json:"action"`
+			UserID string `json:"user_id"`
+		} `json:"changes"`
+	}
+
+	var plan result
+	do(t, ts, "POST", "/team/sync", desired, 200, &plan)
+	require.Equal(t, "plan", plan.Mode)
+	require.Len(t, plan.Changes, 2)
+	for _, c := range plan.Changes {
+		require.Equal(t, "DEACTIVATE_USER", c.Action)
+	}
+
+	var team struct {
+		Members []struct {
+			UserID   string `json:"user_id"`
+			IsActive bool   `json:"is_active"`
+		} `json:"members"`
+	}
+	do(t, ts, "GET", "/team/get?team_name=sync-a", nil, 200, &team)
+	for _, m := range team.Members {
+		require.True(t, m.IsActive, m.UserID)
+	}
+
+	var applied result
+	do(t, ts, "POST", "/team/sync?mode=apply", desired, 200, &applied)
+	require.Equal(t, plan.Changes, applied.Changes)
+
+	do(t, ts, "GET", "/team/get?team_name=sync-a", nil, 200, &team)
+	for _, m := range team.Members {
+		require.Equal(t, m.UserID == "s1" || m.UserID == "s2", m.IsActive, m.UserID)
+	}
+
+	var got struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	do(t, ts, "GET", "/pullRequest/get?pull_request_id=sync-pr", nil, 200, &got)
+	require.NotContains(t, got.PR.AssignedReviewers, "s3")
+	require.NotContains(t, got.PR.AssignedReviewers, "s4")
+
+	var again result
+	do(t, ts, "POST", "/team/sync?mode=apply", desired, 200, &again)
+	require.Empty(t, again.Changes)
+}
+
+func TestE2E_SCIM_ProvisionAndDeactivate(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	for _, id := range []string{"scim-a", "scim-b", "scim-c"} {
+		do(t, ts, "POST", "/scim/v2/Users", map[string]any{"userName": id, "name": map[string]any{"givenName": id}}, 201, nil)
+	}
+	do(t, ts, "POST", "/scim/v2/Users", map[string]any{"userName": "scim-a"}, 409, nil)
+	do(t, ts, "POST", "/scim/v2/Groups", map[string]any{
+		"displayName": "scim-team",
+		"members":     []map[string]any{{"value": "scim-a"}, {"value": "scim-b"}, {"value": "scim-c"}},
+	}, 201, nil)
+
+	var list struct {
+		TotalResults int `json:"totalResults"`
+		Resources    []struct {
+			Groups []struct {
+				Value string `json:"value"`
+			} `json:"groups"`
+		} `json:"Resources"`
+	}
+	do(t, ts, "GET", `/scim/v2/Users?filter=userName+eq+%22scim-a%22`, nil, 200, &list)
+	require.Equal(t, 1, list.TotalResults)
+	require.Equal(t, "scim-team", list.Resources[0].Groups[0].Value)
+
+	var pr struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "scim-pr", "pull_request_name": "x", "author_id": "scim-a",
+	}, 201, &pr)
+	require.ElementsMatch(t, []string{"scim-b", "scim-c"}, pr.PR.AssignedReviewers)
+
+	patch := map[string]any{
+		"schemas":    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		"Operations": []map[string]any{{"op": "replace", "value": map[string]any{"active": false}}},
+	}
+	var user struct {
+		Active bool `json:"active"`
+	}
+	do(t, ts, "PATCH", "/scim/v2/Users/scim-b", patch, 200, &user)
+	require.False(t, user.Active)
+	do(t, ts, "GET", "/pullRequest/get?pull_request_id=scim-pr", nil, 200, &pr)
+	require.Equal(t, []string{"scim-c"}, pr.PR.AssignedReviewers)
+
+	var group struct {
+		Members []struct {
+			Value string `json:"value"`
+		} `json:"members"`
+	}
+	do(t, ts, "PATCH", "/scim/v2/Groups/scim-team", map[string]any{
+		"Operations": []map[string]any{{"op": "remove", "path": `members[value eq "scim-c"]`}},
+	}, 200, &group)
+	require.Len(t, group.Members, 2)
+
+	do(t, ts, "DELETE", "/scim/v2/Users/scim-a", nil, 204, nil)
+	do(t, ts, "GET", "/scim/v2/Users/scim-a", nil, 200, &user)
+	require.False(t, user.Active)
+}
+
+func TestE2E_Snapshot_ExportAndRefuseRestoreIntoNonEmpty(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "snap-team",
+		"members": []map[string]any{
+			{"user_id": "snap1", "username": "Snap1", "is_active": true},
+			{"user_id": "snap2", "username": "Snap2", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "snap-pr", "pull_request_name": "x", "author_id": "snap1",
+	}, 201, nil)
+
+	res, err := http.Get(ts.URL + "/admin/export")
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	type line struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	var lines []line
+	for _, raw := range bytes.Split(bytes.TrimSpace(body), []byte("\n")) {
+		var l line
+		require.NoError(t, json.Unmarshal(raw, &l))
+		lines = append(lines, l)
+	}
+	require.Equal(t, "header", lines[0].Type)
+	require.Equal(t, "footer", lines[len(lines)-1].Type)
+	require.Contains(t, string(body), `"team_name":"snap-team"`)
+	require.Contains(t, string(body), `"pull_request_id":"snap-pr"`)
+
+	var footer struct {
+		Counts struct {
+			Teams int `json:"teams"`
+		} `json:"counts"`
+	}
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1].Data, &footer))
+	teams := 0
+	for _, l := range lines {
+		if l.Type == "team" {
+			teams++
+		}
+	}
+	require.Equal(t, teams, footer.Counts.Teams)
+
+	res2, err := http.Post(ts.URL+"/admin/import-snapshot", "application/x-ndjson", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer func() { _ = res2.Body.Close() }()
+	require.Equal(t, 409, res2.StatusCode)
+	var apiErr struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(res2.Body).Decode(&apiErr))
+	require.Equal(t, "NOT_EMPTY", apiErr.Error.Code)
+}
+
+func TestE2E_Snapshot_RestoreRoundTrip(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "rt-team",
+		"members": []map[string]any{
+			{"user_id": "rt1", "username": "Rt1", "is_active": true},
+			{"user_id": "rt2", "username": "Rt2", "is_active": true},
+			{"user_id": "rt3", "username": "Rt3", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "rt-pr1", "pull_request_name": "x", "author_id": "rt1", "labels": []string{"rt"},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "rt-pr2", "pull_request_name": "y", "author_id": "rt2",
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/merge", map[string]any{"pull_request_id": "rt-pr2"}, 200, nil)
+
+	withEmptyDatabase(t, ts, func(_ *pgxpool.Pool, saved []byte) {
+		lines := bytes.Split(bytes.TrimSpace(saved), []byte("\n"))
+		var footer struct {
+			Data struct {
+				Counts service.SnapshotCounts `json:"counts"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(lines[len(lines)-1], &footer))
+
+		var apiErr struct {
+			Error struct {
+				Code    string               `json:"code"`
+				Details []service.FieldError `json:"details"`
+			} `json:"error"`
+		}
+		truncated := bytes.Join(lines[:len(lines)-1], []byte("\n"))
+		restoreSnapshot(t, ts, truncated, "", 400, &apiErr)
+		require.Equal(t, "VALIDATION_ERROR", apiErr.Error.Code)
+		require.Len(t, apiErr.Error.Details, 1)
+		require.Contains(t, apiErr.Error.Details[0].Reason, "no footer")
+		do(t, ts, "GET", "/team/get?team_name=rt-team", nil, 404, nil)
+
+		var restored struct {
+			Restored service.SnapshotCounts `json:"restored"`
+		}
+		restoreSnapshot(t, ts, saved, "rt-restore", 200, &restored)
+		require.Equal(t, footer.Data.Counts, restored.Restored)
+		// The key is ignored here, so a retry is refused rather than replayed.
+		restoreSnapshot(t, ts, saved, "rt-restore", 409, &apiErr)
+		require.Equal(t, "NOT_EMPTY", apiErr.Error.Code)
+
+		again := bytes.Split(bytes.TrimSpace(exportSnapshot(t, ts)), []byte("\n"))
+		require.Equal(t, len(lines), len(again))
+		for i := 1; i < len(lines); i++ {
+			require.JSONEq(t, string(lines[i]), string(again[i]))
+		}
+
+		var pr struct {
+			PR struct {
+				Labels []string `json:"labels"`
+			} `json:"pr"`
+		}
+		do(t, ts, "GET", "/pullRequest/get?pull_request_id=rt-pr1", nil, 200, &pr)
+		require.Equal(t, []string{"rt"}, pr.PR.Labels)
+		// New assignments continue after the restored review_assignments ids.
+		do(t, ts, "POST", "/pullRequest/create", map[string]any{
+			"pull_request_id": "rt-pr3", "pull_request_name": "z", "author_id": "rt3",
+		}, 201, nil)
+	})
+}
+
+func TestE2E_Snapshot_RestoresVersion1(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	// Version 1 has no team settings, user settings, labels or review times.
+	v1 := strings.Join([]string{
+		`{"type":"header","data":{"format":"reviewer-service-snapshot","version":1,"created_at":"2024-01-05T00:00:00Z"}}`,
+		`{"type":"team","data":{"team_name":"v1-team"}}`,
+		`{"type":"user","data":{"user_id":"v1a","username":"V1a","is_active":true,"team_name":"v1-team"}}`,
+		`{"type":"user","data":{"user_id":"v1b","username":"V1b","is_active":true,"team_name":"v1-team"}}`,
+		`{"type":"pull_request","data":{"pull_request_id":"v1-pr","pull_request_name":"x","author_id":"v1a","status":"OPEN","created_at":"2024-01-02T03:04:05Z","merged_at":null}}`,
+		`{"type":"reviewer","data":{"pull_request_id":"v1-pr","user_id":"v1b","position":1}}`,
+		`{"type":"assignment","data":{"id":7,"pull_request_id":"v1-pr","assigned_user_id":"v1b","action":"AUTO_ASSIGN","created_at":"2024-01-02T03:04:05Z"}}`,
+		`{"type":"footer","data":{"counts":{"teams":1,"users":2,"pull_requests":1,"reviewers":1,"assignments":1}}}`,
+	}, "\n")
+
+	withEmptyDatabase(t, ts, func(pool *pgxpool.Pool, _ []byte) {
+		restoreSnapshot(t, ts, []byte(v1), "", 200, nil)
+
+		var assignedAt time.Time
+		var reviewedAt *time.Time
+		require.NoError(t, pool.QueryRow(context.Background(), `
+			SELECT assigned_at, reviewed_at FROM pr_reviewers WHERE pull_request_id='v1-pr' AND user_id='v1b'
+		`).Scan(&assignedAt, &reviewedAt))
+		require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), assignedAt.UTC())
+		require.Nil(t, reviewedAt)
+
+		var pr struct {
+			PR struct {
+				AssignedReviewers []string `json:"assigned_reviewers"`
+			} `json:"pr"`
+		}
+		do(t, ts, "GET", "/pullRequest/get?pull_request_id=v1-pr", nil, 200, &pr)
+		require.Equal(t, []string{"v1b"}, pr.PR.AssignedReviewers)
+	})
+}
+
+func TestE2E_StatsSeries_BucketsByTeam(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "series-team",
+		"members": []map[string]any{
+			{"user_id": "ser1", "username": "Ser1", "is_active": true},
+			{"user_id": "ser2", "username": "Ser2", "is_active": true},
+			{"user_id": "ser3", "username": "Ser3", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "series-pr", "pull_request_name": "x", "author_id": "ser1",
+	}, 201, nil)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	window := "&from=" + today.AddDate(0, 0, -2).Format(time.DateOnly) + "&to=" + today.AddDate(0, 0, 1).Format(time.DateOnly)
+	var resp struct {
+		Bucket string `json:"bucket"`
+		Series []struct {
+			TeamName string `json:"team_name"`
+			Total    int64  `json:"total"`
+			Points   []struct {
+				Start time.Time `json:"start"`
+				Count int64     `json:"count"`
+			} `json:"points"`
+		} `json:"series_by_teams"`
+	}
+	do(t, ts, "GET", "/stats/get?by=teams&bucket=day&limit=100"+window, nil, 200, &resp)
+	require.Equal(t, "day", resp.Bucket)
+
+	var found bool
+	for _, s := range resp.Series {
+		if s.TeamName != "series-team" {
+			continue
+		}
+		found = true
+		require.EqualValues(t, 2, s.Total)
+		require.Len(t, s.Points, 3)
+		require.EqualValues(t, 0, s.Points[0].Count)
+		require.True(t, s.Points[2].Start.Equal(today))
+		require.EqualValues(t, 2, s.Points[2].Count)
+	}
+	require.True(t, found)
+
+	do(t, ts, "GET", "/stats/get?by=users&bucket=day&from=2000-01-01&to=2020-01-01", nil, 400, nil)
+}
+
+func TestE2E_StatsFairness_CountsIdleMembers(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "fair-team",
+		"members": []map[string]any{
+			{"user_id": "fair1", "username": "Fair1", "is_active": true},
+			{"user_id": "fair2", "username": "Fair2", "is_active": true},
+			{"user_id": "fair3", "username": "Fair3", "is_active": true},
+			{"user_id": "fair4", "username": "Fair4", "is_active": false},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "fair-pr", "pull_request_name": "x", "author_id": "fair1",
+	}, 201, nil)
+
+	var resp struct {
+		Teams []struct {
+			TeamName      string   `json:"team_name"`
+			ActiveMembers int      `json:"active_members"`
+			Assignments   int64    `json:"assignments"`
+			Min           int64    `json:"min"`
+			Max           int64    `json:"max"`
+			Gini          float64  `json:"gini"`
+			MaxMinRatio   *float64 `json:"max_min_ratio"`
+		} `json:"teams"`
+	}
+	do(t, ts, "GET", "/stats/fairness?team_name=fair-team", nil, 200, &resp)
+	require.Len(t, resp.Teams, 1)
+	f := resp.Teams[0]
+	require.Equal(t, "fair-team", f.TeamName)
+	require.Equal(t, 3, f.ActiveMembers)
+	require.EqualValues(t, 2, f.Assignments)
+	require.EqualValues(t, 0, f.Min)
+	require.EqualValues(t, 1, f.Max)
+	require.InDelta(t, 1.0/3, f.Gini, 1e-9)
+	require.Nil(t, f.MaxMinRatio)
+
+	var teams struct {
+		ByTeams []struct {
+			TeamName string `json:"team_name"`
+			Count    int64  `json:"count"`
+		} `json:"by_teams"`
+	}
+	do(t, ts, "GET", "/stats/get?by=teams&sort=id&order=asc&limit=500", nil, 200, &teams)
+	var count int64 = -1
+	for _, s := range teams.ByTeams {
+		if s.TeamName == "fair-team" {
+			count = s.Count
+		}
+	}
+	require.EqualValues(t, 2, count)
+}
+
+func TestE2E_Review_FeedsLatencyStats(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "lat-team",
+		"members": []map[string]any{
+			{"user_id": "lat1", "username": "Lat1", "is_active": true},
+			{"user_id": "lat2", "username": "Lat2", "is_active": true},
+			{"user_id": "lat3", "username": "Lat3", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "lat-pr", "pull_request_name": "x", "author_id": "lat1",
+	}, 201, nil)
+
+	var first, again struct {
+		ReviewedAt time.Time `json:"reviewed_at"`
+	}
+	do(t, ts, "POST", "/pullRequest/review", map[string]any{"pull_request_id": "lat-pr", "user_id": "lat2"}, 200, &first)
+	do(t, ts, "POST", "/pullRequest/review", map[string]any{"pull_request_id": "lat-pr", "user_id": "lat2"}, 200, &again)
+	require.True(t, first.ReviewedAt.Equal(again.ReviewedAt))
+	do(t, ts, "POST", "/pullRequest/review", map[string]any{"pull_request_id": "lat-pr", "user_id": "lat1"}, 409, nil)
+	do(t, ts, "POST", "/pullRequest/review", map[string]any{"pull_request_id": "missing", "user_id": "lat2"}, 404, nil)
+	do(t, ts, "POST", "/pullRequest/merge", map[string]any{"pull_request_id": "lat-pr"}, 200, nil)
+
+	type latency struct {
+		Count int64    `json:"count"`
+		P50   *float64 `json:"p50"`
+	}
+	var teams struct {
+		ByTeams []struct {
+			TeamName          string  `json:"team_name"`
+			PRs               int64   `json:"prs"`
+			TimeToFirstReview latency `json:"time_to_first_review"`
+			TimeToMerge       latency `json:"time_to_merge"`
+		} `json:"by_teams"`
+	}
+	do(t, ts, "GET", "/stats/latency?team_name=lat-team", nil, 200, &teams)
+	require.Len(t, teams.ByTeams, 1)
+	require.EqualValues(t, 1, teams.ByTeams[0].PRs)
+	require.EqualValues(t, 1, teams.ByTeams[0].TimeToFirstReview.Count)
+	require.NotNil(t, teams.ByTeams[0].TimeToFirstReview.P50)
+	require.EqualValues(t, 1, teams.ByTeams[0].TimeToMerge.Count)
+
+	var reviewers struct {
+		ByReviewers []struct {
+			UserID       string  `json:"user_id"`
+			Assigned     int64   `json:"assigned"`
+			Pending      int64   `json:"pending"`
+			TimeToReview latency `json:"time_to_review"`
+		} `json:"by_reviewers"`
+	}
+	do(t, ts, "GET", "/stats/latency?by=reviewers&team_name=lat-team", nil, 200, &reviewers)
+	require.Len(t, reviewers.ByReviewers, 2)
+	for _, r := range reviewers.ByReviewers {
+		require.EqualValues(t, 1, r.Assigned)
+		require.EqualValues(t, 0, r.Pending, "merged PRs are not pending")
+		require.Equal(t, r.UserID == "lat2", r.TimeToReview.Count == 1, r.UserID)
+	}
+}
+
+func TestE2E_StaleReviews_RemindThenEscalate(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+	pool, err := db.NewPool(context.Background(), config.FromEnv().DatabaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	svc := service.New(repo.New(pool))
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "sla-team",
+		"members": []map[string]any{
+			{"user_id": "sla1", "username": "Sla1", "is_active": true},
+			{"user_id": "sla2", "username": "Sla2", "is_active": true},
+			{"user_id": "sla3", "username": "Sla3", "is_active": true},
+			{"user_id": "sla4", "username": "Sla4", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{
+		"team_name": "sla-team", "review_sla_seconds": 2, "escalate_after_seconds": 1,
+	}, 400, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{
+		"team_name": "sla-team", "review_sla_seconds": 1, "escalate_after_seconds": 2,
+	}, 200, nil)
+	var created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "sla-pr", "pull_request_name": "x", "author_id": "sla1",
+	}, 201, &created)
+	require.Len(t, created.PR.AssignedReviewers, 2)
+	reviewed := created.PR.AssignedReviewers[0]
+	do(t, ts, "POST", "/pullRequest/review", map[string]any{"pull_request_id": "sla-pr", "user_id": reviewed}, 200, nil)
+
+	var events []service.ReviewEvent
+	collect := func(e service.ReviewEvent) {
+		if e.PullRequestID == "sla-pr" {
+			events = append(events, e)
+		}
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	_, err = svc.ProcessStaleReviews(context.Background(), collect)
+	require.NoError(t, err)
+	_, err = svc.ProcessStaleReviews(context.Background(), collect)
+	require.NoError(t, err)
+	require.Len(t, events, 1, "one reminder per assignment")
+	require.Equal(t, service.EventReminder, events[0].Type)
+	require.Equal(t, created.PR.AssignedReviewers[1], events[0].UserID)
+
+	events = nil
+	time.Sleep(time.Second)
+	_, err = svc.ProcessStaleReviews(context.Background(), collect)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, service.EventEscalated, events[0].Type)
+	require.Equal(t, created.PR.AssignedReviewers[1], events[0].UserID)
+	require.NotContains(t, []string{"sla1", reviewed, events[0].UserID}, events[0].ReplacedBy)
+
+	var pr struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	do(t, ts, "GET", "/pullRequest/get?pull_request_id=sla-pr", nil, 200, &pr)
+	require.ElementsMatch(t, []string{reviewed, events[0].ReplacedBy}, pr.PR.AssignedReviewers)
+}
+
+func TestE2E_IdempotencyPurge_DeletesExpiredKeys(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := db.NewPool(ctx, config.FromEnv().DatabaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	svc := service.New(repo.New(pool))
+
+	path := fmt.Sprintf("/e2e/purge/%d", time.Now().UnixNano())
+	_, err = svc.IdempotencyBegin(ctx, "expired", "POST", path, "h", -time.Minute)
+	require.NoError(t, err)
+	_, err = svc.IdempotencyBegin(ctx, "live", "POST", path, "h", time.Hour)
+	require.NoError(t, err)
+
+	job := scheduler.IdempotencyPurge(svc, time.Hour)
+	_, err = job.Run(ctx)
+	require.NoError(t, err)
+
+	var keys []string
+	rows, err := pool.Query(ctx, `SELECT idem_key FROM idempotency_keys WHERE path=$1 ORDER BY idem_key`, path)
+	require.NoError(t, err)
+	for rows.Next() {
+		var k string
+		require.NoError(t, rows.Scan(&k))
+		keys = append(keys, k)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []string{"live"}, keys)
+}
+
+func TestE2E_IdempotencyBegin_SurvivesReleasedKeys(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := db.NewPool(ctx, config.FromEnv().DatabaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	svc := service.New(repo.New(pool))
+
+	// Two clients retry the same key; whoever owns it releases it at once,
+	// as after a 5xx, so the other often finds the key gone between its
+	// reservation and the lookup.
+	path := fmt.Sprintf("/e2e/release/%d", time.Now().UnixNano())
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			for range 200 {
+				stored, err := svc.IdempotencyBegin(ctx, "k", "POST", path, "h", time.Hour)
+				switch {
+				case errors.Is(err, service.ErrIdempotencyInProgress):
+				case err != nil:
+					errs <- err
+					return
+				case stored == nil:
+					if err := svc.IdempotencyRelease(ctx, "k", "POST", path); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+			errs <- nil
+		}()
+	}
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+}
+
+func TestE2E_Jobs_TriggerAndLeaderElection(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	pool, err := db.NewPool(context.Background(), config.FromEnv().DatabaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+	rp := repo.New(pool)
+
+	release := make(chan struct{})
+	var ticks atomic.Int32
+	tickJob := fmt.Sprintf("e2e_tick_%d", time.Now().UnixNano())
+	newScheduler := func() *scheduler.Scheduler {
+		s := scheduler.New(rp)
+		s.Register(scheduler.Job{Name: "e2e_block", Run: func(ctx context.Context) (any, error) {
+			<-release
+			return map[string]int{"done": 1}, nil
+		}})
+		s.Register(scheduler.Job{Name: tickJob, Interval: time.Hour, Run: func(ctx context.Context) (any, error) {
+			ticks.Add(1)
+			return nil, nil
+		}})
+		return s
+	}
+	s1, s2 := newScheduler(), newScheduler()
+
+	spec, err := httpx.LoadSpec(context.Background(), "../../openapi.yml")
+	require.NoError(t, err)
+	handler, err := httpx.NewRouter(service.New(rp), httpx.Options{Spec: spec, ValidateResponses: true, Jobs: s1})
+	require.NoError(t, err)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var started struct {
+		Run struct {
+			ID     int64  `json:"id"`
+			Status string `json:"status"`
+		} `json:"run"`
+	}
+	do(t, ts, "POST", "/admin/jobs/trigger", map[string]any{"name": "e2e_block"}, 202, &started)
+	require.Equal(t, "RUNNING", started.Run.Status)
+	do(t, ts, "POST", "/admin/jobs/trigger", map[string]any{"name": "e2e_block"}, 409, nil)
+	_, err = s2.Trigger(context.Background(), "e2e_block")
+	require.ErrorIs(t, err, service.ErrJobRunning, "the job lock is shared by all instances")
+	do(t, ts, "POST", "/admin/jobs/trigger", map[string]any{"name": "missing"}, 404, nil)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		var runs struct {
+			Runs []struct {
+				ID     int64           `json:"id"`
+				Status string          `json:"status"`
+				Result json.RawMessage `json:"result"`
+			} `json:"runs"`
+		}
+		do(t, ts, "GET", "/admin/jobs/runs?name=e2e_block&limit=1", nil, 200, &runs)
+		return len(runs.Runs) == 1 && runs.Runs[0].ID == started.Run.ID &&
+			runs.Runs[0].Status == "SUCCEEDED" && string(runs.Runs[0].Result) == `{"done":1}`
+	}, 5*time.Second, 50*time.Millisecond)
+
+	ctx1, stop1 := context.WithCancel(context.Background())
+	ctx2, stop2 := context.WithCancel(context.Background())
+	done1, done2 := make(chan struct{}), make(chan struct{})
+	go func() { s1.Run(ctx1); close(done1) }()
+	go func() { s2.Run(ctx2); close(done2) }()
+	defer func() { stop1(); stop2(); <-done1; <-done2 }()
+
+	require.Eventually(t, func() bool { return s1.IsLeader() || s2.IsLeader() }, 5*time.Second, 50*time.Millisecond)
+	require.False(t, s1.IsLeader() && s2.IsLeader())
+	leaderStop, leaderDone, follower := stop1, done1, s2
+	if s2.IsLeader() {
+		leaderStop, leaderDone, follower = stop2, done2, s1
+	}
+	require.Eventually(t, func() bool { return ticks.Load() == 1 }, 5*time.Second, 50*time.Millisecond)
+
+	leaderStop()
+	<-leaderDone
+	require.Eventually(t, follower.IsLeader, 5*time.Second, 50*time.Millisecond)
+	time.Sleep(1500 * time.Millisecond)
+	require.EqualValues(t, 1, ticks.Load(), "a new leader does not rerun a job within its interval")
+}
+
+func TestE2E_Capacity_SkipsReviewersAtLimit(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "cap-team",
+		"members": []map[string]any{
+			{"user_id": "cap1", "username": "Cap1", "is_active": true},
+			{"user_id": "cap2", "username": "Cap2", "is_active": true},
+			{"user_id": "cap3", "username": "Cap3", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "cap2", "max_open_reviews": -1}, 400, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "cap2", "max_open_reviews": 1}, 200, nil)
+	var settings struct {
+		Settings struct {
+			MaxOpenReviews *int `json:"max_open_reviews"`
+		} `json:"settings"`
+	}
+	do(t, ts, "GET", "/users/getSettings?user_id=cap2", nil, 200, &settings)
+	require.NotNil(t, settings.Settings.MaxOpenReviews)
+	require.Equal(t, 1, *settings.Settings.MaxOpenReviews)
+
+	type created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+		CapacityShortfall *service.CapacityShortfall `json:"capacity_shortfall"`
+	}
+	var first created
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "cap-pr1", "pull_request_name": "x", "author_id": "cap1",
+	}, 201, &first)
+	require.ElementsMatch(t, []string{"cap2", "cap3"}, first.PR.AssignedReviewers)
+	require.Nil(t, first.CapacityShortfall)
+
+	var second created
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "cap-pr2", "pull_request_name": "x", "author_id": "cap1",
+	}, 201, &second)
+	require.Equal(t, []string{"cap3"}, second.PR.AssignedReviewers)
+	require.Equal(t, &service.CapacityShortfall{Wanted: 2, Assigned: 1, AtCapacity: []string{"cap2"}}, second.CapacityShortfall)
+
+	var errResp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	do(t, ts, "POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "cap-pr2", "old_user_id": "cap3",
+	}, 409, &errResp)
+	require.Equal(t, "NO_CANDIDATE", errResp.Error.Code)
+	require.Contains(t, errResp.Error.Message, "max_open_reviews")
+
+	do(t, ts, "POST", "/pullRequest/merge", map[string]any{"pull_request_id": "cap-pr1"}, 200, nil)
+	do(t, ts, "POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "cap-pr2", "old_user_id": "cap3",
+	}, 200, nil)
+
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "cap2", "max_open_reviews": 0}, 200, &settings)
+	require.Nil(t, settings.Settings.MaxOpenReviews)
+}
+
+func TestE2E_ReviewWeight_ZeroNeverPicked(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "weight-team",
+		"members": []map[string]any{
+			{"user_id": "w1", "username": "W1", "is_active": true},
+			{"user_id": "w2", "username": "W2", "is_active": true},
+			{"user_id": "w3", "username": "W3", "is_active": true},
+			{"user_id": "w4", "username": "W4", "is_active": true},
+		},
+	}, 201, nil)
+	var settings struct {
+		Settings struct {
+			ReviewWeight int `json:"review_weight"`
+		} `json:"settings"`
+	}
+	do(t, ts, "GET", "/users/getSettings?user_id=w2", nil, 200, &settings)
+	require.Equal(t, 1, settings.Settings.ReviewWeight)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "w2", "review_weight": 101}, 400, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "w2", "review_weight": 0}, 200, &settings)
+	require.Equal(t, 0, settings.Settings.ReviewWeight)
+
+	for i := range 5 {
+		var created struct {
+			PR struct {
+				AssignedReviewers []string `json:"assigned_reviewers"`
+			} `json:"pr"`
+		}
+		id := fmt.Sprintf("weight-pr%d", i)
+		do(t, ts, "POST", "/pullRequest/create", map[string]any{
+			"pull_request_id": id, "pull_request_name": "x", "author_id": "w1",
+		}, 201, &created)
+		require.ElementsMatch(t, []string{"w3", "w4"}, created.PR.AssignedReviewers)
+	}
+	do(t, ts, "POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "weight-pr0", "old_user_id": "w3",
+	}, 409, nil)
+}
+
+func TestE2E_RoundRobin_TakesTurns(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "rr-team",
+		"members": []map[string]any{
+			{"user_id": "rr1", "username": "Rr1", "is_active": true},
+			{"user_id": "rr2", "username": "Rr2", "is_active": true},
+			{"user_id": "rr3", "username": "Rr3", "is_active": true},
+			{"user_id": "rr4", "username": "Rr4", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{"team_name": "rr-team", "assignment_strategy": "BOGUS"}, 400, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{"team_name": "rr-team", "assignment_strategy": "ROUND_ROBIN"}, 200, nil)
+
+	type created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	create := func(id string) []string {
+		var res created
+		do(t, ts, "POST", "/pullRequest/create", map[string]any{
+			"pull_request_id": id, "pull_request_name": "x", "author_id": "rr1",
+		}, 201, &res)
+		return res.PR.AssignedReviewers
+	}
+	require.ElementsMatch(t, []string{"rr2", "rr3"}, create("rr-pr1"))
+	require.ElementsMatch(t, []string{"rr4", "rr2"}, create("rr-pr2"))
+	require.ElementsMatch(t, []string{"rr3", "rr4"}, create("rr-pr3"))
+
+	var settings struct {
+		Settings struct {
+			RotationCursor *string `json:"rotation_cursor"`
+		} `json:"settings"`
+	}
+	do(t, ts, "GET", "/team/getSettings?team_name=rr-team", nil, 200, &settings)
+	require.NotNil(t, settings.Settings.RotationCursor)
+	require.Equal(t, "rr4", *settings.Settings.RotationCursor)
+
+	do(t, ts, "POST", "/users/setIsActive", map[string]any{"user_id": "rr3", "is_active": false}, 200, nil)
+	require.ElementsMatch(t, []string{"rr2", "rr4"}, create("rr-pr4"))
+	do(t, ts, "POST", "/users/setIsActive", map[string]any{"user_id": "rr3", "is_active": true}, 200, nil)
+
+	// Three concurrent PRs take the next six turns: two for every member.
+	results := make(chan []string, 3)
+	for i := range 3 {
+		go func() {
+			body, _ := json.Marshal(map[string]any{
+				"pull_request_id": fmt.Sprintf("rr-concurrent-%d", i), "pull_request_name": "x", "author_id": "rr1",
+			})
+			res, err := http.Post(ts.URL+"/pullRequest/create", "application/json", bytes.NewReader(body))
+			if err != nil {
+				results <- nil
+				return
+			}
+			defer func() { _ = res.Body.Close() }()
+			var c created
+			if res.StatusCode != 201 || json.NewDecoder(res.Body).Decode(&c) != nil {
+				results <- nil
+				return
+			}
+			results <- c.PR.AssignedReviewers
+		}()
+	}
+	counts := map[string]int{}
+	for range 3 {
+		revs := <-results
+		require.Len(t, revs, 2)
+		for _, id := range revs {
+			counts[id]++
+		}
+	}
+	require.Equal(t, map[string]int{"rr2": 2, "rr3": 2, "rr4": 2}, counts)
+}
+
+func TestE2E_ReviewerRules_RequireSenior(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "rules-team",
+		"members": []map[string]any{
+			{"user_id": "ru1", "username": "Ru1", "is_active": true},
+			{"user_id": "ru2", "username": "Ru2", "is_active": true},
+			{"user_id": "ru3", "username": "Ru3", "is_active": true},
+			{"user_id": "ru4", "username": "Ru4", "is_active": true},
+			{"user_id": "ru5", "username": "Ru5", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "ru2", "level": "Senior"}, 200, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "ru5", "skills": []string{"db", "go"}}, 200, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{
+		"team_name":      "rules-team",
+		"reviewer_rules": []map[string]any{{"level": "senior", "skill": "db"}},
+	}, 400, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{
+		"team_name":      "rules-team",
+		"reviewer_rules": []map[string]any{{"level": "senior"}, {"skill": "db", "if_label": "db"}},
+	}, 200, nil)
+
+	type created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+			Labels            []string `json:"labels"`
+		} `json:"pr"`
+	}
+	for i := range 5 {
+		var res created
+		do(t, ts, "POST", "/pullRequest/create", map[string]any{
+			"pull_request_id": fmt.Sprintf("rules-pr%d", i), "pull_request_name": "x", "author_id": "ru1",
+		}, 201, &res)
+		require.Contains(t, res.PR.AssignedReviewers, "ru2")
+	}
+	var res created
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "rules-db", "pull_request_name": "x", "author_id": "ru1", "labels": []string{"DB"},
+	}, 201, &res)
+	require.ElementsMatch(t, []string{"ru2", "ru5"}, res.PR.AssignedReviewers)
+	require.Equal(t, []string{"db"}, res.PR.Labels)
+
+	do(t, ts, "POST", "/users/setIsActive", map[string]any{"user_id": "ru2", "is_active": false}, 200, nil)
+	var errRes struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "rules-none", "pull_request_name": "x", "author_id": "ru1",
+	}, 409, &errRes)
+	require.Equal(t, "RULES_UNSATISFIABLE", errRes.Error.Code)
+	do(t, ts, "GET", "/pullRequest/get?pull_request_id=rules-none", nil, 404, nil)
+}
+
+func TestE2E_PairAvoidance_PrefersFreshReviewers(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	members := []string{"pa2", "pa3", "pa4"}
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "pairs-team",
+		"members": []map[string]any{
+			{"user_id": "pa1", "username": "Pa1", "is_active": true},
+			{"user_id": "pa2", "username": "Pa2", "is_active": true},
+			{"user_id": "pa3", "username": "Pa3", "is_active": true},
+			{"user_id": "pa4", "username": "Pa4", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{"team_name": "pairs-team", "pair_avoidance_prs": 101}, 400, nil)
+	do(t, ts, "POST", "/team/setSettings", map[string]any{"team_name": "pairs-team", "pair_avoidance_prs": 1}, 200, nil)
+
+	// Every PR has to take the one member left out of the previous PR.
+	var prev []string
+	for i := range 6 {
+		var res struct {
+			PR struct {
+				AssignedReviewers []string `json:"assigned_reviewers"`
+			} `json:"pr"`
+		}
+		do(t, ts, "POST", "/pullRequest/create", map[string]any{
+			"pull_request_id": fmt.Sprintf("pairs-pr%d", i), "pull_request_name": "x", "author_id": "pa1",
+		}, 201, &res)
+		revs := res.PR.AssignedReviewers
+		require.Len(t, revs, 2)
+		for _, id := range members {
+			if prev != nil && !slices.Contains(prev, id) {
+				require.Contains(t, revs, id)
+			}
+		}
+		prev = revs
+	}
+}
+
+func TestE2E_Blocklists_AppliedToEverySelection(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "block-team",
+		"members": []map[string]any{
+			{"user_id": "bl1", "username": "Bl1", "is_active": true},
+			{"user_id": "bl2", "username": "Bl2", "is_active": true},
+			{"user_id": "bl3", "username": "Bl3", "is_active": true},
+			{"user_id": "bl4", "username": "Bl4", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "bl2", "blocked_authors": []string{"bl2"}}, 400, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "bl2", "blocked_authors": []string{"nobody"}}, 400, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "bl2", "blocked_authors": []string{"bl1"}}, 200, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "bl3", "blocked_labels": []string{"HR"}}, 200, nil)
+
+	type created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	var res created
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "block-pr1", "pull_request_name": "x", "author_id": "bl1",
+	}, 201, &res)
+	require.ElementsMatch(t, []string{"bl3", "bl4"}, res.PR.AssignedReviewers)
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "block-pr2", "pull_request_name": "x", "author_id": "bl1", "labels": []string{"hr"},
+	}, 201, &res)
+	require.Equal(t, []string{"bl4"}, res.PR.AssignedReviewers)
+
+	// Reassignment cannot fall back on bl2 either.
+	do(t, ts, "POST", "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "block-pr2", "old_user_id": "bl4",
+	}, 409, nil)
+
+	// Nor can safe reassignment: bl3 is dropped from block-pr1.
+	var deact struct {
+		SafeReassign struct {
+			Reassigned int `json:"reassigned"`
+			Removed    int `json:"removed"`
+		} `json:"safe_reassign"`
+	}
+	do(t, ts, "POST", "/team/deactivate", map[string]any{"team_name": "block-team", "user_ids": []string{"bl3"}}, 200, &deact)
+	require.Equal(t, 0, deact.SafeReassign.Reassigned)
+	require.Equal(t, 1, deact.SafeReassign.Removed)
+}
+
+func TestE2E_WorkingHours_PrefersOnlineReviewers(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	do(t, ts, "POST", "/team/add", map[string]any{
+		"team_name": "hours-team",
+		"members": []map[string]any{
+			{"user_id": "wh1", "username": "Wh1", "is_active": true},
+			{"user_id": "wh2", "username": "Wh2", "is_active": true},
+			{"user_id": "wh3", "username": "Wh3", "is_active": true},
+			{"user_id": "wh4", "username": "Wh4", "is_active": true},
+			{"user_id": "wh5", "username": "Wh5", "is_active": true},
+		},
+	}, 201, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "wh2", "timezone": "Mars/Olympus"}, 400, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "wh2", "work_start": "09:00"}, 400, nil)
+	do(t, ts, "POST", "/users/setSettings", map[string]any{"user_id": "wh2", "work_start": "9am", "work_end": "18:00"}, 400, nil)
+	var invalid struct {
+		Error struct {
+			Details []service.FieldError `json:"details"`
+		} `json:"error"`
+	}
+	do(t, ts, "POST", "/users/setSettings", map[string]any{
+		"user_id": "wh2", "level": "not a tag", "skills": []string{"go", "!"}, "timezone": "Mars/Olympus", "work_start": "9am", "work_end": "18:00",
+	}, 400, &invalid)
+	var fields []string
+	for _, d := range invalid.Error.Details {
+		fields = append(fields, d.Field)
+	}
+	require.Equal(t, []string{"level", "skills", "timezone", "work_start"}, fields)
+
+	// wh2 and wh5 are at work now, in different time zones; wh3 and wh4 are not.
+	clock := func(tz string, d time.Duration) string {
+		loc, err := time.LoadLocation(tz)
+		require.NoError(t, err)
+		return time.Now().In(loc).Add(d).Format("15:04")
+	}
+	hours := map[string][2]time.Duration{
+		"wh2": {-time.Hour, time.Hour}, "wh3": {2 * time.Hour, 3 * time.Hour},
+		"wh4": {-3 * time.Hour, -2 * time.Hour}, "wh5": {-time.Hour, time.Hour},
+	}
+	zones := map[string]string{"wh2": "Asia/Kathmandu", "wh3": "America/Los_Angeles", "wh4": "UTC", "wh5": "Europe/Berlin"}
+	for id, h := range hours {
+		do(t, ts, "POST", "/users/setSettings", map[string]any{
+			"user_id": id, "timezone": zones[id], "work_start": clock(zones[id], h[0]), "work_end": clock(zones[id], h[1]),
+		}, 200, nil)
+	}
+	do(t, ts, "POST", "/team/setSettings", map[string]any{"team_name": "hours-team", "prefer_working_hours": true}, 200, nil)
+
+	type created struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+		} `json:"pr"`
+	}
+	for i := range 4 {
+		var res created
+		do(t, ts, "POST", "/pullRequest/create", map[string]any{
+			"pull_request_id": fmt.Sprintf("hours-pr%d", i), "pull_request_name": "x", "author_id": "wh1",
+		}, 201, &res)
+		require.ElementsMatch(t, []string{"wh2", "wh5"}, res.PR.AssignedReviewers)
+	}
+
+	// With one member at work, the other place goes to someone who is not.
+	do(t, ts, "POST", "/users/setIsActive", map[string]any{"user_id": "wh5", "is_active": false}, 200, nil)
+	var res created
+	do(t, ts, "POST", "/pullRequest/create", map[string]any{
+		"pull_request_id": "hours-pr-last", "pull_request_name": "x", "author_id": "wh1",
+	}, 201, &res)
+	require.Len(t, res.PR.AssignedReviewers, 2)
+	require.Contains(t, res.PR.AssignedReviewers, "wh2")
+}
+
+func TestE2E_GRPC_CreatePRAndMapsErrors(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	pool, err := db.NewPool(context.Background(), config.FromEnv().DatabaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	lis := bufconn.Listen(1 << 20)
+	svc := service.New(repo.New(pool))
+	srv := grpcx.NewServer(svc)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	cl := reviewerv1.NewReviewerServiceClient(conn)
+	ctx := context.Background()
+
+	added, err := cl.AddTeam(ctx, &reviewerv1.AddTeamRequest{
+		TeamName: "grpc",
+		Members: []*reviewerv1.TeamMember{
+			{UserId: "g1", Username: "G1"},
+			{UserId: "g2", Username: "G2"},
+		},
+	})
+	require.NoError(t, err)
+	for _, m := range added.GetTeam().GetMembers() {
+		require.True(t, m.GetIsActive(), m.GetUserId())
+	}
+
+	created, err := cl.CreatePullRequest(ctx, &reviewerv1.CreatePullRequestRequest{
+		PullRequestId: "pr-grpc", PullRequestName: "gRPC PR", AuthorId: "g1", Labels: []string{" Backend ", "backend"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"g2"}, created.GetPr().GetAssignedReviewers())
+	require.Equal(t, []string{"backend"}, created.GetPr().GetLabels())
+	require.Nil(t, created.GetCapacityShortfall())
+
+	limit := 1
+	_, err = svc.UserSetSettings(ctx, "g2", service.UserSettingsPatch{MaxOpenReviews: &limit})
+	require.NoError(t, err)
+	short, err := cl.CreatePullRequest(ctx, &reviewerv1.CreatePullRequestRequest{
+		PullRequestId: "pr-grpc-short", PullRequestName: "gRPC PR", AuthorId: "g1",
+	})
+	require.NoError(t, err)
+	require.Empty(t, short.GetPr().GetAssignedReviewers())
+	require.Equal(t, int32(2), short.GetCapacityShortfall().GetWanted())
+	require.Equal(t, int32(0), short.GetCapacityShortfall().GetAssigned())
+	require.Equal(t, []string{"g2"}, short.GetCapacityShortfall().GetAtCapacity())
+
+	_, err = cl.AddTeam(ctx, &reviewerv1.AddTeamRequest{
+		TeamName: "grpc-cap",
+		Members: []*reviewerv1.TeamMember{
+			{UserId: "gc1", Username: "GC1", IsActive: proto.Bool(true)},
+			{UserId: "gc2", Username: "GC2", IsActive: proto.Bool(true)},
+			{UserId: "gc3", Username: "GC3", IsActive: proto.Bool(true)},
+			{UserId: "gc4", Username: "GC4", IsActive: proto.Bool(true)},
+		},
+	})
+	require.NoError(t, err)
+	none := 0
+	_, err = svc.UserSetSettings(ctx, "gc4", service.UserSettingsPatch{MaxOpenReviews: &none})
+	require.NoError(t, err)
+	_, err = cl.CreatePullRequest(ctx, &reviewerv1.CreatePullRequestRequest{
+		PullRequestId: "pr-grpc-cap", PullRequestName: "gRPC PR", AuthorId: "gc1",
+	})
+	require.NoError(t, err)
+	deactivated, err := cl.DeactivateTeamUsers(ctx, &reviewerv1.DeactivateTeamUsersRequest{
+		TeamName: "grpc-cap", UserIds: []string{"gc2"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), deactivated.GetRemoved())
+	require.Equal(t, int32(1), deactivated.GetRemovedAtCapacity())
+
+	_, err = cl.CreatePullRequest(ctx, &reviewerv1.CreatePullRequestRequest{
+		PullRequestId: "pr-grpc", PullRequestName: "gRPC PR", AuthorId: "g1",
+	})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = cl.GetTeam(ctx, &reviewerv1.GetTeamRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func doWithKey(t *testing.T, ts *httptest.Server, path, key string, body any, want int, out any) http.Header {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	req, _ := http.NewRequest("POST", ts.URL+path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	require.Equal(t, want, res.StatusCode)
+	if out != nil {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+	}
+	return res.Header
+}
+
+// exportSnapshot returns a full snapshot from /admin/export.
+func exportSnapshot(t *testing.T, ts *httptest.Server) []byte {
+	t.Helper()
+	res, err := http.Get(ts.URL + "/admin/export")
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	require.Equal(t, 200, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return body
+}
+
+// restoreSnapshot posts body to /admin/import-snapshot, with key as the
+// Idempotency-Key unless it is empty.
+func restoreSnapshot(t *testing.T, ts *httptest.Server, body []byte, key string, want int, out any) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", ts.URL+"/admin/import-snapshot", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	require.Equal(t, want, res.StatusCode)
+	if out != nil {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+	}
+}
+
+// withEmptyDatabase runs fn with the snapshot tables emptied; saved is the
+// snapshot taken before, which is restored again once fn returns so that the
+// other tests keep their data.
+func withEmptyDatabase(t *testing.T, ts *httptest.Server, fn func(pool *pgxpool.Pool, saved []byte)) {
+	t.Helper()
+	pool, err := db.NewPool(context.Background(), config.FromEnv().DatabaseURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	truncate := func() {
+		_, err := pool.Exec(context.Background(), `TRUNCATE teams, users, prs, pr_reviewers, review_assignments CASCADE`)
+		require.NoError(t, err)
+	}
+	saved := exportSnapshot(t, ts)
+	truncate()
+	defer func() {
+		truncate()
+		restoreSnapshot(t, ts, saved, "", 200, nil)
+	}()
+	fn(pool, saved)
+}
+
 func do(t *testing.T, ts *httptest.Server, method, path string, body any, want int, out any) {
 	t.Helper()
 	var buf bytes.Buffer
@@ -0,0 +1,83 @@
+// Package graphqlx serves a read-only GraphQL view over teams, users, pull
+// requests and assignment stats for dashboards.
+package graphqlx
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	maxDepth = 8
+	// maxParallelism bounds concurrently resolved fields. It also caps how
+	// many keys a loader can collect per batch, so keep it near the largest
+	// list a dashboard renders.
+	maxParallelism = 200
+)
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// NewHandler returns an http.Handler executing GraphQL POST requests against r.
+// Every request gets its own set of loaders.
+func NewHandler(r *repo.Repo) http.Handler {
+	schema := graphql.MustParseSchema(schemaSDL, &rootResolver{r: r},
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxParallelism(maxParallelism),
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body request
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeErr(w, service.Invalid("body", "malformed JSON: "+err.Error()))
+			return
+		}
+		if body.Query == "" {
+			writeErr(w, service.Invalid("query", "required"))
+			return
+		}
+
+		ctx := withLoaders(req.Context(), newLoaders(r))
+		resp := schema.Exec(ctx, body.Query, body.OperationName, body.Variables)
+
+		// Resolver errors carry service/repo errors; expose them with the same
+		// codes as the REST API instead of raw database messages.
+		for _, qe := range resp.Errors {
+			if qe.ResolverError == nil {
+				continue
+			}
+			apiErr := service.ToHTTPError(qe.ResolverError)
+			if apiErr.Status >= 500 {
+				log.Printf("graphql: internal error at %v: %v", qe.Path, qe.ResolverError)
+			}
+			qe.Message = apiErr.Message
+			qe.Extensions = map[string]any{"code": apiErr.Code}
+			if len(apiErr.Details) > 0 {
+				qe.Extensions["details"] = apiErr.Details
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	apiErr := service.ToHTTPError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": apiErr})
+}
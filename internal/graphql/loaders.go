@@ -0,0 +1,119 @@
+package graphqlx
+
+import (
+	"context"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+)
+
+// loaderWait is how long a loader collects keys before issuing its batch query.
+const loaderWait = 2 * time.Millisecond
+
+// loaders batch the per-object lookups of one GraphQL request, so that
+// resolving a list of N objects costs one query per field instead of N.
+type loaders struct {
+	users   *dataloader.Loader[string, *models.User]
+	prs     *dataloader.Loader[string, *models.PullRequest]
+	members *dataloader.Loader[string, []models.User]
+	reviews *dataloader.Loader[reviewsKey, []models.PullRequest]
+	counts  *dataloader.Loader[string, int64]
+}
+
+type reviewsKey struct {
+	UserID string
+	Status models.PRStatus
+	Limit  int
+}
+
+type loadersKey struct{}
+
+func newLoaders(r *repo.Repo) *loaders {
+	return &loaders{
+		users: newLoader(func(ctx context.Context, ids []string) (map[string]*models.User, error) {
+			list, err := r.ListUsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[string]*models.User, len(list))
+			for i := range list {
+				res[list[i].UserID] = &list[i]
+			}
+			return res, nil
+		}),
+		prs: newLoader(func(ctx context.Context, ids []string) (map[string]*models.PullRequest, error) {
+			list, err := r.ListPRsByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[string]*models.PullRequest, len(list))
+			for i := range list {
+				res[list[i].PullRequestID] = &list[i]
+			}
+			return res, nil
+		}),
+		members: newLoader(func(ctx context.Context, teams []string) (map[string][]models.User, error) {
+			list, err := r.ListUsersByTeams(ctx, teams)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[string][]models.User, len(teams))
+			for _, u := range list {
+				res[u.TeamName] = append(res[u.TeamName], u)
+			}
+			return res, nil
+		}),
+		reviews: newLoader(func(ctx context.Context, keys []reviewsKey) (map[reviewsKey][]models.PullRequest, error) {
+			// One query per distinct (status, limit) pair; in practice a
+			// request uses a single pair for all users.
+			groups := map[reviewsKey][]string{}
+			for _, k := range keys {
+				g := reviewsKey{Status: k.Status, Limit: k.Limit}
+				groups[g] = append(groups[g], k.UserID)
+			}
+			res := make(map[reviewsKey][]models.PullRequest, len(keys))
+			for g, ids := range groups {
+				byUser, err := r.ListPRsByReviewers(ctx, ids, g.Status, g.Limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, id := range ids {
+					res[reviewsKey{UserID: id, Status: g.Status, Limit: g.Limit}] = byUser[id]
+				}
+			}
+			return res, nil
+		}),
+		counts: newLoader(func(ctx context.Context, ids []string) (map[string]int64, error) {
+			return r.AssignmentCountsByUsers(ctx, ids, nil, nil)
+		}),
+	}
+}
+
+// newLoader adapts a map-returning batch query to a dataloader. Keys missing
+// from the map resolve to the zero value.
+func newLoader[K comparable, V any](fetch func(context.Context, []K) (map[K]V, error)) *dataloader.Loader[K, V] {
+	batch := func(ctx context.Context, keys []K) []*dataloader.Result[V] {
+		m, err := fetch(ctx, keys)
+		res := make([]*dataloader.Result[V], len(keys))
+		for i, k := range keys {
+			if err != nil {
+				res[i] = &dataloader.Result[V]{Error: err}
+				continue
+			}
+			res[i] = &dataloader.Result[V]{Data: m[k]}
+		}
+		return res
+	}
+	return dataloader.NewBatchedLoader(batch, dataloader.WithWait[K, V](loaderWait))
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
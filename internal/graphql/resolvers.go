@@ -0,0 +1,275 @@
+package graphqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/jackc/pgx/v5"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+type rootResolver struct {
+	r *repo.Repo
+}
+
+// -------- Query --------
+
+func (q *rootResolver) Team(ctx context.Context, args struct{ Name string }) (*teamResolver, error) {
+	t, err := q.r.GetTeam(ctx, args.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &teamResolver{name: t.TeamName}, nil
+}
+
+func (q *rootResolver) Teams(ctx context.Context) ([]*teamResolver, error) {
+	names, err := q.r.ListTeamNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*teamResolver, len(names))
+	for i, n := range names {
+		res[i] = &teamResolver{name: n}
+	}
+	return res, nil
+}
+
+func (q *rootResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	u, err := loadersFrom(ctx).users.Load(ctx, string(args.ID))()
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return &userResolver{u: *u}, nil
+}
+
+func (q *rootResolver) PullRequest(ctx context.Context, args struct{ ID graphql.ID }) (*prResolver, error) {
+	pr, err := loadersFrom(ctx).prs.Load(ctx, string(args.ID))()
+	if err != nil || pr == nil {
+		return nil, err
+	}
+	return &prResolver{pr: *pr}, nil
+}
+
+func (q *rootResolver) PullRequests(ctx context.Context, args struct {
+	Status     *string
+	AuthorID   *graphql.ID
+	TeamName   *string
+	ReviewerID *graphql.ID
+	First      int32
+}) ([]*prResolver, error) {
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	f := repo.PRFilter{
+		AuthorID:   idValue(args.AuthorID),
+		TeamName:   strValue(args.TeamName),
+		ReviewerID: idValue(args.ReviewerID),
+		Status:     models.PRStatus(strValue(args.Status)),
+	}
+	list, _, err := q.r.ListPRs(ctx, f, repo.Page{Limit: int(args.First), Sort: repo.SortCreatedAt, Desc: true})
+	if err != nil {
+		return nil, err
+	}
+	return prResolvers(list), nil
+}
+
+func (q *rootResolver) AssignmentStats(ctx context.Context, args struct {
+	From  *graphql.Time
+	To    *graphql.Time
+	First int32
+}) ([]*assignmentStatResolver, error) {
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	p := repo.Page{Limit: int(args.First), Sort: repo.SortCount, Desc: true}
+	if args.From != nil {
+		t := args.From.UTC()
+		p.From = &t
+	}
+	if args.To != nil {
+		t := args.To.UTC()
+		p.To = &t
+	}
+	if p.From != nil && p.To != nil && !p.From.Before(*p.To) {
+		return nil, service.Invalid("from", "must be before to")
+	}
+
+	stats, _, err := q.r.StatsByUsers(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*assignmentStatResolver, len(stats))
+	for i, s := range stats {
+		res[i] = &assignmentStatResolver{userID: s.UserID, count: s.Count}
+	}
+	return res, nil
+}
+
+// -------- Team --------
+
+type teamResolver struct {
+	name string
+}
+
+func (t *teamResolver) Name() string { return t.name }
+
+func (t *teamResolver) Members(ctx context.Context, args struct{ ActiveOnly bool }) ([]*userResolver, error) {
+	users, err := loadersFrom(ctx).members.Load(ctx, t.name)()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*userResolver, 0, len(users))
+	for _, u := range users {
+		if args.ActiveOnly && !u.IsActive {
+			continue
+		}
+		res = append(res, &userResolver{u: u})
+	}
+	return res, nil
+}
+
+// -------- User --------
+
+type userResolver struct {
+	u models.User
+}
+
+func (u *userResolver) ID() graphql.ID   { return graphql.ID(u.u.UserID) }
+func (u *userResolver) Username() string { return u.u.Username }
+func (u *userResolver) IsActive() bool   { return u.u.IsActive }
+
+func (u *userResolver) Team() *teamResolver {
+	if u.u.TeamName == "" {
+		return nil
+	}
+	return &teamResolver{name: u.u.TeamName}
+}
+
+func (u *userResolver) Reviews(ctx context.Context, args struct {
+	Status *string
+	First  int32
+}) ([]*prResolver, error) {
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	key := reviewsKey{UserID: u.u.UserID, Status: models.PRStatus(strValue(args.Status)), Limit: int(args.First)}
+	list, err := loadersFrom(ctx).reviews.Load(ctx, key)()
+	if err != nil {
+		return nil, err
+	}
+	return prResolvers(list), nil
+}
+
+func (u *userResolver) AssignmentCount(ctx context.Context) (int32, error) {
+	n, err := loadersFrom(ctx).counts.Load(ctx, u.u.UserID)()
+	return int32(n), err
+}
+
+// -------- PullRequest --------
+
+type prResolver struct {
+	pr models.PullRequest
+}
+
+func (p *prResolver) ID() graphql.ID { return graphql.ID(p.pr.PullRequestID) }
+func (p *prResolver) Name() string   { return p.pr.PullRequestName }
+func (p *prResolver) Status() string { return string(p.pr.Status) }
+func (p *prResolver) Labels() []string {
+	if p.pr.Labels == nil {
+		return []string{}
+	}
+	return p.pr.Labels
+}
+
+func (p *prResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: p.pr.CreatedAt}
+}
+
+func (p *prResolver) MergedAt() *graphql.Time {
+	if p.pr.MergedAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *p.pr.MergedAt}
+}
+
+func (p *prResolver) Author(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, p.pr.AuthorID)
+}
+
+func (p *prResolver) Reviewers(ctx context.Context) ([]*userResolver, error) {
+	users, errs := loadersFrom(ctx).users.LoadMany(ctx, p.pr.AssignedReviewers)()
+	res := make([]*userResolver, 0, len(users))
+	for i, u := range users {
+		if len(errs) > i && errs[i] != nil {
+			return nil, errs[i]
+		}
+		if u != nil {
+			res = append(res, &userResolver{u: *u})
+		}
+	}
+	return res, nil
+}
+
+func prResolvers(list []models.PullRequest) []*prResolver {
+	res := make([]*prResolver, len(list))
+	for i := range list {
+		res[i] = &prResolver{pr: list[i]}
+	}
+	return res
+}
+
+// -------- AssignmentStat --------
+
+type assignmentStatResolver struct {
+	userID string
+	count  int64
+}
+
+func (s *assignmentStatResolver) User(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, s.userID)
+}
+
+func (s *assignmentStatResolver) Count() int32 { return int32(s.count) }
+
+// -------- helpers --------
+
+// loadUser resolves a user that must exist, e.g. a PR author.
+func loadUser(ctx context.Context, id string) (*userResolver, error) {
+	u, err := loadersFrom(ctx).users.Load(ctx, id)()
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("user %q: %w", id, service.ErrNotFound)
+	}
+	return &userResolver{u: *u}, nil
+}
+
+func checkFirst(n int32) error {
+	if n < 1 || n > repo.MaxPageLimit {
+		return service.Invalid("first", fmt.Sprintf("must be between 1 and %d", repo.MaxPageLimit))
+	}
+	return nil
+}
+
+func strValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func idValue(id *graphql.ID) string {
+	if id == nil {
+		return ""
+	}
+	return string(*id)
+}
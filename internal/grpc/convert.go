@@ -0,0 +1,131 @@
+package grpcx
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"reviewer-service/internal/grpc/reviewerv1"
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+func teamToProto(t models.Team) *reviewerv1.Team {
+	out := &reviewerv1.Team{TeamName: t.TeamName}
+	for _, m := range t.Members {
+		out.Members = append(out.Members, &reviewerv1.TeamMember{
+			UserId:   m.UserID,
+			Username: m.Username,
+			IsActive: proto.Bool(m.IsActive),
+		})
+	}
+	return out
+}
+
+func membersFromProto(in []*reviewerv1.TeamMember) []models.TeamMember {
+	out := make([]models.TeamMember, 0, len(in))
+	for _, m := range in {
+		out = append(out, models.TeamMember{
+			UserID:   m.GetUserId(),
+			Username: m.GetUsername(),
+			IsActive: m.IsActive == nil || m.GetIsActive(),
+		})
+	}
+	return out
+}
+
+func userToProto(u models.User) *reviewerv1.User {
+	return &reviewerv1.User{
+		UserId:   u.UserID,
+		Username: u.Username,
+		TeamName: u.TeamName,
+		IsActive: u.IsActive,
+	}
+}
+
+func prToProto(pr models.PullRequest) *reviewerv1.PullRequest {
+	out := &reviewerv1.PullRequest{
+		PullRequestId:     pr.PullRequestID,
+		PullRequestName:   pr.PullRequestName,
+		AuthorId:          pr.AuthorID,
+		Status:            statusToProto(pr.Status),
+		AssignedReviewers: pr.AssignedReviewers,
+		CreatedAt:         timestamppb.New(pr.CreatedAt),
+		Labels:            pr.Labels,
+	}
+	if pr.MergedAt != nil {
+		out.MergedAt = timestamppb.New(*pr.MergedAt)
+	}
+	return out
+}
+
+func shortfallToProto(s *service.CapacityShortfall) *reviewerv1.CapacityShortfall {
+	if s == nil {
+		return nil
+	}
+	return &reviewerv1.CapacityShortfall{
+		Wanted:     int32(s.Wanted),
+		Assigned:   int32(s.Assigned),
+		AtCapacity: s.AtCapacity,
+	}
+}
+
+func statusToProto(s models.PRStatus) reviewerv1.PullRequestStatus {
+	switch s {
+	case models.PROpen:
+		return reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_OPEN
+	case models.PRMerged:
+		return reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_MERGED
+	default:
+		return reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_UNSPECIFIED
+	}
+}
+
+func statusFromProto(s reviewerv1.PullRequestStatus) models.PRStatus {
+	switch s {
+	case reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_OPEN:
+		return models.PROpen
+	case reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_MERGED:
+		return models.PRMerged
+	default:
+		return ""
+	}
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime().UTC()
+	return &t
+}
+
+// pageFromProto converts a proto Page, adding its errors to v; the first of
+// sorts is the default sort key.
+func pageFromProto(v *service.Validation, p *reviewerv1.Page, sorts ...string) repo.Page {
+	out := repo.Page{
+		Limit:  int(p.GetLimit()),
+		Cursor: p.GetCursor(),
+		Sort:   sorts[0],
+		Desc:   !p.GetAscending(),
+		From:   timeFromProto(p.GetFrom()),
+		To:     timeFromProto(p.GetTo()),
+	}
+	if p.GetLimit() < 0 || p.GetLimit() > repo.MaxPageLimit {
+		v.Add("page.limit", fmt.Sprintf("must be between 0 and %d", repo.MaxPageLimit))
+	}
+	if s := p.GetSort(); s != "" {
+		if !slices.Contains(sorts, s) {
+			v.Add("page.sort", fmt.Sprintf("must be one of %v", sorts))
+		}
+		out.Sort = s
+	}
+	if out.From != nil && out.To != nil && !out.From.Before(*out.To) {
+		v.Add("page.from", "must be before to")
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package grpcx
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"reviewer-service/internal/service"
+)
+
+const errorDomain = "reviewer-service"
+
+// errorInterceptor maps service errors to gRPC statuses. The HTTP error code
+// travels in an ErrorInfo detail so both transports expose the same taxonomy.
+func errorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return nil, err
+	}
+	return nil, toStatus(info.FullMethod, err).Err()
+}
+
+func recoverInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("grpc: panic in %s: %v", info.FullMethod, p)
+			err = status.Error(codes.Internal, "INTERNAL: internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func toStatus(method string, err error) *status.Status {
+	apiErr := service.ToHTTPError(err)
+	if apiErr.Status >= 500 {
+		log.Printf("grpc: internal error in %s: %v", method, err)
+	}
+
+	st := status.New(grpcCode(apiErr.Code), apiErr.Code+": "+apiErr.Message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: apiErr.Code, Domain: errorDomain}}
+	if len(apiErr.Details) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range apiErr.Details {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: f.Reason,
+			})
+		}
+		details = append(details, br)
+	}
+	if withDetails, derr := st.WithDetails(details...); derr == nil {
+		return withDetails
+	}
+	return st
+}
+
+func grpcCode(code string) codes.Code {
+	switch code {
+	case "VALIDATION_ERROR":
+		return codes.InvalidArgument
+	case "NOT_FOUND":
+		return codes.NotFound
+	case "TEAM_EXISTS", "USER_EXISTS", "PR_EXISTS":
+		return codes.AlreadyExists
+	case "PR_MERGED", "NOT_ASSIGNED", "NO_CANDIDATE", "NOT_EMPTY", "JOB_RUNNING", "RULES_UNSATISFIABLE":
+		return codes.FailedPrecondition
+	case "CONFLICT", "IDEMPOTENCY_CONFLICT", "IDEMPOTENCY_IN_PROGRESS":
+		return codes.Aborted
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,228 @@
+package grpcx
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"reviewer-service/internal/grpc/reviewerv1"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+type Server struct {
+	reviewerv1.UnimplementedReviewerServiceServer
+	svc *service.Service
+}
+
+// NewServer returns a gRPC server exposing svc. Service errors are translated
+// to gRPC statuses by an interceptor, so handlers return them unchanged.
+func NewServer(svc *service.Service) *grpc.Server {
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(recoverInterceptor, errorInterceptor))
+	reviewerv1.RegisterReviewerServiceServer(s, &Server{svc: svc})
+	return s
+}
+
+// -------- Teams --------
+
+func (s *Server) AddTeam(ctx context.Context, req *reviewerv1.AddTeamRequest) (*reviewerv1.AddTeamResponse, error) {
+	var v service.Validation
+	v.Required("team_name", req.GetTeamName())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	team, err := s.svc.TeamAdd(ctx, req.GetTeamName(), membersFromProto(req.GetMembers()))
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.AddTeamResponse{Team: teamToProto(team)}, nil
+}
+
+func (s *Server) GetTeam(ctx context.Context, req *reviewerv1.GetTeamRequest) (*reviewerv1.GetTeamResponse, error) {
+	var v service.Validation
+	v.Required("team_name", req.GetTeamName())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	team, err := s.svc.TeamGet(ctx, req.GetTeamName())
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.GetTeamResponse{Team: teamToProto(team)}, nil
+}
+
+func (s *Server) DeactivateTeamUsers(ctx context.Context, req *reviewerv1.DeactivateTeamUsersRequest) (*reviewerv1.DeactivateTeamUsersResponse, error) {
+	var v service.Validation
+	v.Required("team_name", req.GetTeamName())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	res, err := s.svc.TeamDeactivate(ctx, req.GetTeamName(), req.GetUserIds())
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.DeactivateTeamUsersResponse{
+		TeamName:          res.TeamName,
+		Deactivated:       res.Deactivated,
+		Reassigned:        int32(res.SafeReassign.Reassigned),
+		Removed:           int32(res.SafeReassign.Removed),
+		RemovedAtCapacity: int32(res.SafeReassign.RemovedAtCapacity),
+	}, nil
+}
+
+// -------- Users --------
+
+func (s *Server) SetUserActive(ctx context.Context, req *reviewerv1.SetUserActiveRequest) (*reviewerv1.SetUserActiveResponse, error) {
+	var v service.Validation
+	v.Required("user_id", req.GetUserId())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	u, err := s.svc.UserSetIsActive(ctx, req.GetUserId(), req.GetIsActive())
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.SetUserActiveResponse{User: userToProto(u)}, nil
+}
+
+func (s *Server) ListUserReviews(ctx context.Context, req *reviewerv1.ListUserReviewsRequest) (*reviewerv1.ListUserReviewsResponse, error) {
+	var v service.Validation
+	v.Required("user_id", req.GetUserId())
+	page := pageFromProto(&v, req.GetPage(), repo.SortCreatedAt, repo.SortID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	list, next, err := s.svc.UserGetReview(ctx, req.GetUserId(), statusFromProto(req.GetStatus()), page)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*reviewerv1.PullRequestShort, len(list))
+	for i, pr := range list {
+		out[i] = &reviewerv1.PullRequestShort{
+			PullRequestId:   pr.PullRequestID,
+			PullRequestName: pr.PullRequestName,
+			AuthorId:        pr.AuthorID,
+			Status:          statusToProto(pr.Status),
+		}
+	}
+	return &reviewerv1.ListUserReviewsResponse{UserId: req.GetUserId(), PullRequests: out, NextCursor: next}, nil
+}
+
+// -------- PRs --------
+
+func (s *Server) CreatePullRequest(ctx context.Context, req *reviewerv1.CreatePullRequestRequest) (*reviewerv1.CreatePullRequestResponse, error) {
+	var v service.Validation
+	v.Required("pull_request_id", req.GetPullRequestId())
+	v.Required("pull_request_name", req.GetPullRequestName())
+	v.Required("author_id", req.GetAuthorId())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	pr, shortfall, err := s.svc.PRCreate(ctx, req.GetPullRequestId(), req.GetPullRequestName(), req.GetAuthorId(), req.GetLabels())
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.CreatePullRequestResponse{Pr: prToProto(pr), CapacityShortfall: shortfallToProto(shortfall)}, nil
+}
+
+func (s *Server) MergePullRequest(ctx context.Context, req *reviewerv1.MergePullRequestRequest) (*reviewerv1.MergePullRequestResponse, error) {
+	var v service.Validation
+	v.Required("pull_request_id", req.GetPullRequestId())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	pr, err := s.svc.PRMerge(ctx, req.GetPullRequestId())
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.MergePullRequestResponse{Pr: prToProto(pr)}, nil
+}
+
+func (s *Server) ReassignReviewer(ctx context.Context, req *reviewerv1.ReassignReviewerRequest) (*reviewerv1.ReassignReviewerResponse, error) {
+	var v service.Validation
+	v.Required("pull_request_id", req.GetPullRequestId())
+	v.Required("old_user_id", req.GetOldUserId())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	pr, replacedBy, err := s.svc.PRReassign(ctx, req.GetPullRequestId(), req.GetOldUserId())
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.ReassignReviewerResponse{Pr: prToProto(pr), ReplacedBy: replacedBy}, nil
+}
+
+func (s *Server) GetPullRequest(ctx context.Context, req *reviewerv1.GetPullRequestRequest) (*reviewerv1.GetPullRequestResponse, error) {
+	var v service.Validation
+	v.Required("pull_request_id", req.GetPullRequestId())
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	pr, err := s.svc.PRGet(ctx, req.GetPullRequestId())
+	if err != nil {
+		return nil, err
+	}
+	return &reviewerv1.GetPullRequestResponse{Pr: prToProto(pr)}, nil
+}
+
+func (s *Server) ListPullRequests(ctx context.Context, req *reviewerv1.ListPullRequestsRequest) (*reviewerv1.ListPullRequestsResponse, error) {
+	var v service.Validation
+	page := pageFromProto(&v, req.GetPage(), repo.SortCreatedAt, repo.SortID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	f := repo.PRFilter{
+		AuthorID:     req.GetAuthorId(),
+		TeamName:     req.GetTeamName(),
+		ReviewerID:   req.GetReviewerId(),
+		Status:       statusFromProto(req.GetStatus()),
+		NameContains: req.GetQ(),
+		MergedFrom:   timeFromProto(req.GetMergedFrom()),
+		MergedTo:     timeFromProto(req.GetMergedTo()),
+	}
+	list, next, err := s.svc.PRList(ctx, f, page)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*reviewerv1.PullRequest, len(list))
+	for i, pr := range list {
+		out[i] = prToProto(pr)
+	}
+	return &reviewerv1.ListPullRequestsResponse{PullRequests: out, NextCursor: next}, nil
+}
+
+// -------- Stats --------
+
+func (s *Server) GetUserStats(ctx context.Context, req *reviewerv1.GetUserStatsRequest) (*reviewerv1.GetUserStatsResponse, error) {
+	var v service.Validation
+	page := pageFromProto(&v, req.GetPage(), repo.SortCount, repo.SortID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	st, next, err := s.svc.StatsByUsers(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*reviewerv1.UserAssignStat, len(st))
+	for i, x := range st {
+		out[i] = &reviewerv1.UserAssignStat{UserId: x.UserID, Count: x.Count}
+	}
+	return &reviewerv1.GetUserStatsResponse{ByUsers: out, NextCursor: next}, nil
+}
+
+func (s *Server) GetPullRequestStats(ctx context.Context, req *reviewerv1.GetPullRequestStatsRequest) (*reviewerv1.GetPullRequestStatsResponse, error) {
+	var v service.Validation
+	page := pageFromProto(&v, req.GetPage(), repo.SortCount, repo.SortID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	st, next, err := s.svc.StatsByPRs(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*reviewerv1.PullRequestAssignStat, len(st))
+	for i, x := range st {
+		out[i] = &reviewerv1.PullRequestAssignStat{PullRequestId: x.PullRequestID, Count: x.Count}
+	}
+	return &reviewerv1.GetPullRequestStatsResponse{ByPrs: out, NextCursor: next}, nil
+}
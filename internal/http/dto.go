@@ -1,32 +1,122 @@
 package httpx
 
+import (
+	"strconv"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/service"
+)
+
 // /team/add
 type TeamAddReq struct {
 	TeamName string `json:"team_name"`
 	Members  []struct {
 		UserID   string `json:"user_id"`
 		Username string `json:"username"`
-		IsActive bool   `json:"is_active"`
+		IsActive *bool  `json:"is_active"`
 	} `json:"members"`
 }
 
+func (r TeamAddReq) Validate() error {
+	var v service.Validation
+	v.Required("team_name", r.TeamName)
+	if r.Members == nil {
+		v.Add("members", "required")
+	}
+	seen := map[string]bool{}
+	for i, m := range r.Members {
+		prefix := "members[" + strconv.Itoa(i) + "]."
+		v.Required(prefix+"user_id", m.UserID)
+		v.Required(prefix+"username", m.Username)
+		if m.UserID != "" && seen[m.UserID] {
+			v.Add(prefix+"user_id", "duplicate user_id")
+		}
+		seen[m.UserID] = true
+	}
+	return v.Err()
+}
+
 // /team/deactivate
 type TeamDeactivateReq struct {
 	TeamName string   `json:"team_name"`
 	UserIDs  []string `json:"user_ids,omitempty"`
 }
 
+func (r TeamDeactivateReq) Validate() error {
+	var v service.Validation
+	v.Required("team_name", r.TeamName)
+	for i, id := range r.UserIDs {
+		v.Required("user_ids["+strconv.Itoa(i)+"]", id)
+	}
+	return v.Err()
+}
+
+// /team/setSettings
+type TeamSetSettingsReq struct {
+	TeamName             string                 `json:"team_name"`
+	ReviewSLASeconds     *int                   `json:"review_sla_seconds"`
+	EscalateAfterSeconds *int                   `json:"escalate_after_seconds"`
+	AssignmentStrategy   *string                `json:"assignment_strategy"`
+	ReviewerRules        *[]models.ReviewerRule `json:"reviewer_rules"`
+	PairAvoidancePRs     *int                   `json:"pair_avoidance_prs"`
+	PreferWorkingHours   *bool                  `json:"prefer_working_hours"`
+}
+
+func (r TeamSetSettingsReq) Validate() error {
+	var v service.Validation
+	v.Required("team_name", r.TeamName)
+	return v.Err()
+}
+
 // /users/setIsActive
 type SetIsActiveReq struct {
 	UserID   string `json:"user_id"`
-	IsActive bool   `json:"is_active"`
+	IsActive *bool  `json:"is_active"`
+}
+
+func (r SetIsActiveReq) Validate() error {
+	var v service.Validation
+	v.Required("user_id", r.UserID)
+	if r.IsActive == nil {
+		v.Add("is_active", "required")
+	}
+	return v.Err()
+}
+
+// /users/setSettings
+type UserSetSettingsReq struct {
+	UserID         string    `json:"user_id"`
+	MaxOpenReviews *int      `json:"max_open_reviews"`
+	ReviewWeight   *int      `json:"review_weight"`
+	Level          *string   `json:"level"`
+	Skills         *[]string `json:"skills"`
+	BlockedAuthors *[]string `json:"blocked_authors"`
+	BlockedLabels  *[]string `json:"blocked_labels"`
+	Timezone       *string   `json:"timezone"`
+	WorkStart      *string   `json:"work_start"`
+	WorkEnd        *string   `json:"work_end"`
+}
+
+func (r UserSetSettingsReq) Validate() error {
+	var v service.Validation
+	v.Required("user_id", r.UserID)
+	return v.Err()
 }
 
 // /pullRequest/create
 type PRCreateReq struct {
-	PullRequestID   string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	PullRequestID   string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	Labels          []string `json:"labels"`
+}
+
+func (r PRCreateReq) Validate() error {
+	var v service.Validation
+	v.Required("pull_request_id", r.PullRequestID)
+	v.Required("pull_request_name", r.PullRequestName)
+	v.Required("author_id", r.AuthorID)
+	return v.Err()
 }
 
 // /pullRequest/merge
@@ -34,8 +124,45 @@ type PRMergeReq struct {
 	PullRequestID string `json:"pull_request_id"`
 }
 
+func (r PRMergeReq) Validate() error {
+	var v service.Validation
+	v.Required("pull_request_id", r.PullRequestID)
+	return v.Err()
+}
+
 // /pullRequest/reassign
 type PRReassignReq struct {
 	PullRequestID string `json:"pull_request_id"`
 	OldUserID     string `json:"old_user_id"`
 }
+
+func (r PRReassignReq) Validate() error {
+	var v service.Validation
+	v.Required("pull_request_id", r.PullRequestID)
+	v.Required("old_user_id", r.OldUserID)
+	return v.Err()
+}
+
+// /pullRequest/review
+type PRReviewReq struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+func (r PRReviewReq) Validate() error {
+	var v service.Validation
+	v.Required("pull_request_id", r.PullRequestID)
+	v.Required("user_id", r.UserID)
+	return v.Err()
+}
+
+// /admin/jobs/trigger
+type AdminJobTriggerReq struct {
+	Name string `json:"name"`
+}
+
+func (r AdminJobTriggerReq) Validate() error {
+	var v service.Validation
+	v.Required("name", r.Name)
+	return v.Err()
+}
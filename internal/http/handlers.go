@@ -2,31 +2,35 @@ package httpx
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/scheduler"
 	"reviewer-service/internal/service"
 )
 
 type Handlers struct {
-	svc *service.Service
+	svc  *service.Service
+	jobs *scheduler.Scheduler
 }
 
 // -------- Teams --------
 
 func (h *Handlers) TeamAdd(w http.ResponseWriter, r *http.Request) {
 	var req TeamAddReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TeamName == "" {
-		writeErr(w, 400, "NOT_FOUND", "invalid body")
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
+	// Members without is_active are active, as in /admin/import.
 	members := make([]models.TeamMember, 0, len(req.Members))
 	for _, m := range req.Members {
 		members = append(members, models.TeamMember{
 			UserID:   m.UserID,
 			Username: m.Username,
-			IsActive: m.IsActive,
+			IsActive: m.IsActive == nil || *m.IsActive,
 		})
 	}
 
@@ -39,9 +43,8 @@ func (h *Handlers) TeamAdd(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) TeamGet(w http.ResponseWriter, r *http.Request) {
-	teamName := r.URL.Query().Get("team_name")
-	if teamName == "" {
-		writeErr(w, 400, "NOT_FOUND", "team_name required")
+	teamName, ok := requireQuery(w, r, "team_name")
+	if !ok {
 		return
 	}
 	team, err := h.svc.TeamGet(r.Context(), teamName)
@@ -52,10 +55,42 @@ func (h *Handlers) TeamGet(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, team)
 }
 
+func (h *Handlers) TeamGetSettings(w http.ResponseWriter, r *http.Request) {
+	teamName, ok := requireQuery(w, r, "team_name")
+	if !ok {
+		return
+	}
+	settings, err := h.svc.TeamGetSettings(r.Context(), teamName)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"settings": settings})
+}
+
+func (h *Handlers) TeamSetSettings(w http.ResponseWriter, r *http.Request) {
+	var req TeamSetSettingsReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	settings, err := h.svc.TeamSetSettings(r.Context(), req.TeamName, service.TeamSettingsPatch{
+		ReviewSLASeconds:     req.ReviewSLASeconds,
+		EscalateAfterSeconds: req.EscalateAfterSeconds,
+		AssignmentStrategy:   (*models.AssignmentStrategy)(req.AssignmentStrategy),
+		ReviewerRules:        req.ReviewerRules,
+		PairAvoidancePRs:     req.PairAvoidancePRs,
+		PreferWorkingHours:   req.PreferWorkingHours,
+	})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"settings": settings})
+}
+
 func (h *Handlers) TeamDeactivate(w http.ResponseWriter, r *http.Request) {
 	var req TeamDeactivateReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TeamName == "" {
-		writeErr(w, 400, "NOT_FOUND", "invalid body")
+	if !decodeBody(w, r, &req) {
 		return
 	}
 	res, err := h.svc.TeamDeactivate(r.Context(), req.TeamName, req.UserIDs)
@@ -70,11 +105,10 @@ func (h *Handlers) TeamDeactivate(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) UserSetIsActive(w http.ResponseWriter, r *http.Request) {
 	var req SetIsActiveReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
-		writeErr(w, 400, "NOT_FOUND", "invalid body")
+	if !decodeBody(w, r, &req) {
 		return
 	}
-	user, err := h.svc.UserSetIsActive(r.Context(), req.UserID, req.IsActive)
+	user, err := h.svc.UserSetIsActive(r.Context(), req.UserID, *req.IsActive)
 	if err != nil {
 		writeSvcErr(w, err)
 		return
@@ -82,13 +116,58 @@ func (h *Handlers) UserSetIsActive(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, map[string]any{"user": user})
 }
 
+func (h *Handlers) UserGetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireQuery(w, r, "user_id")
+	if !ok {
+		return
+	}
+	settings, err := h.svc.UserGetSettings(r.Context(), userID)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"settings": settings})
+}
+
+func (h *Handlers) UserSetSettings(w http.ResponseWriter, r *http.Request) {
+	var req UserSetSettingsReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	settings, err := h.svc.UserSetSettings(r.Context(), req.UserID, service.UserSettingsPatch{
+		MaxOpenReviews: req.MaxOpenReviews,
+		ReviewWeight:   req.ReviewWeight,
+		Level:          req.Level,
+		Skills:         req.Skills,
+		BlockedAuthors: req.BlockedAuthors,
+		BlockedLabels:  req.BlockedLabels,
+		Timezone:       req.Timezone,
+		WorkStart:      req.WorkStart,
+		WorkEnd:        req.WorkEnd,
+	})
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"settings": settings})
+}
+
 func (h *Handlers) UserGetReview(w http.ResponseWriter, r *http.Request) {
-	uid := r.URL.Query().Get("user_id")
-	if uid == "" {
-		writeErr(w, 400, "NOT_FOUND", "user_id required")
+	uid, ok := requireQuery(w, r, "user_id")
+	if !ok {
+		return
+	}
+	status, err := parseStatusParam(r.URL.Query())
+	if err != nil {
+		writeSvcErr(w, err)
 		return
 	}
-	list, err := h.svc.UserGetReview(r.Context(), uid)
+	page, err := parsePage(r.URL.Query(), repo.SortCreatedAt, repo.SortID)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	list, next, err := h.svc.UserGetReview(r.Context(), uid, status, page)
 	if err != nil {
 		writeSvcErr(w, err)
 		return
@@ -96,6 +175,7 @@ func (h *Handlers) UserGetReview(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, map[string]any{
 		"user_id":       uid,
 		"pull_requests": list,
+		"next_cursor":   nextCursor(next),
 	})
 }
 
@@ -103,25 +183,26 @@ func (h *Handlers) UserGetReview(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) PRCreate(w http.ResponseWriter, r *http.Request) {
 	var req PRCreateReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
-		req.PullRequestID == "" || req.PullRequestName == "" || req.AuthorID == "" {
-		writeErr(w, 400, "NOT_FOUND", "invalid body")
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	pr, err := h.svc.PRCreate(r.Context(), req.PullRequestID, req.PullRequestName, req.AuthorID)
+	pr, shortfall, err := h.svc.PRCreate(r.Context(), req.PullRequestID, req.PullRequestName, req.AuthorID, req.Labels)
 
 	if err != nil {
 		writeSvcErr(w, err)
 		return
 	}
-	writeJSON(w, 201, map[string]any{"pr": pr})
+	resp := map[string]any{"pr": pr}
+	if shortfall != nil {
+		resp["capacity_shortfall"] = shortfall
+	}
+	writeJSON(w, 201, resp)
 }
 
 func (h *Handlers) PRMerge(w http.ResponseWriter, r *http.Request) {
 	var req PRMergeReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PullRequestID == "" {
-		writeErr(w, 400, "NOT_FOUND", "invalid body")
+	if !decodeBody(w, r, &req) {
 		return
 	}
 	pr, err := h.svc.PRMerge(r.Context(), req.PullRequestID)
@@ -134,9 +215,7 @@ func (h *Handlers) PRMerge(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) PRReassign(w http.ResponseWriter, r *http.Request) {
 	var req PRReassignReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
-		req.PullRequestID == "" || req.OldUserID == "" {
-		writeErr(w, 400, "NOT_FOUND", "invalid body")
+	if !decodeBody(w, r, &req) {
 		return
 	}
 	pr, replacedBy, err := h.svc.PRReassign(r.Context(), req.PullRequestID, req.OldUserID)
@@ -147,6 +226,71 @@ func (h *Handlers) PRReassign(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, map[string]any{"pr": pr, "replaced_by": replacedBy})
 }
 
+func (h *Handlers) PRReview(w http.ResponseWriter, r *http.Request) {
+	var req PRReviewReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	pr, at, err := h.svc.PRReview(r.Context(), req.PullRequestID, req.UserID)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"pr": pr, "reviewed_at": at})
+}
+
+func (h *Handlers) PRGet(w http.ResponseWriter, r *http.Request) {
+	prID, ok := requireQuery(w, r, "pull_request_id")
+	if !ok {
+		return
+	}
+	pr, err := h.svc.PRGet(r.Context(), prID)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"pr": pr})
+}
+
+func (h *Handlers) PRList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status, err := parseStatusParam(q)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	page, err := parsePage(q, repo.SortCreatedAt, repo.SortID)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	f := repo.PRFilter{
+		AuthorID:     q.Get("author_id"),
+		TeamName:     q.Get("team_name"),
+		ReviewerID:   q.Get("reviewer_id"),
+		Status:       status,
+		NameContains: q.Get("q"),
+	}
+	if f.MergedFrom, err = parseTimeParam(q, "merged_from"); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	if f.MergedTo, err = parseTimeParam(q, "merged_to"); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+
+	list, next, err := h.svc.PRList(r.Context(), f, page)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{
+		"pull_requests": list,
+		"next_cursor":   nextCursor(next),
+	})
+}
+
 // -------- Stats --------
 
 func (h *Handlers) StatsGet(w http.ResponseWriter, r *http.Request) {
@@ -155,40 +299,168 @@ func (h *Handlers) StatsGet(w http.ResponseWriter, r *http.Request) {
 		by = "users"
 	}
 
+	page, err := parsePage(r.URL.Query(), repo.SortCount, repo.SortID)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+
+	if bucket := r.URL.Query().Get("bucket"); bucket != "" {
+		h.statsSeries(w, r, by, bucket, page)
+		return
+	}
+
 	switch by {
 	case "users":
-		st, err := h.svc.StatsByUsers(r.Context())
+		st, next, err := h.svc.StatsByUsers(r.Context(), page)
 		if err != nil {
 			writeSvcErr(w, err)
 			return
 		}
-		writeJSON(w, 200, map[string]any{"by_users": st})
+		writeJSON(w, 200, map[string]any{"by_users": st, "next_cursor": nextCursor(next)})
 	case "prs":
-		st, err := h.svc.StatsByPRs(r.Context())
+		st, next, err := h.svc.StatsByPRs(r.Context(), page)
+		if err != nil {
+			writeSvcErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"by_prs": st, "next_cursor": nextCursor(next)})
+	case "teams":
+		st, next, err := h.svc.StatsByTeams(r.Context(), page)
+		if err != nil {
+			writeSvcErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"by_teams": st, "next_cursor": nextCursor(next)})
+	default:
+		writeSvcErr(w, service.Invalid("by", "must be users, prs or teams"))
+	}
+}
+
+func (h *Handlers) StatsFairness(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, err := parseTimeParam(q, "from")
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	to, err := parseTimeParam(q, "to")
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		writeSvcErr(w, service.Invalid("from", "must be before to"))
+		return
+	}
+
+	teams, err := h.svc.Fairness(r.Context(), q.Get("team_name"), from, to)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"teams": teams})
+}
+
+func (h *Handlers) StatsLatency(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := repo.LatencyFilter{TeamName: q.Get("team_name")}
+	var err error
+	if f.From, err = parseTimeParam(q, "from"); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	if f.To, err = parseTimeParam(q, "to"); err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		writeSvcErr(w, service.Invalid("from", "must be before to"))
+		return
+	}
+
+	switch by := q.Get("by"); by {
+	case "", "teams":
+		st, err := h.svc.LatencyByTeams(r.Context(), f)
+		if err != nil {
+			writeSvcErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"by_teams": st})
+	case "reviewers":
+		st, err := h.svc.LatencyByReviewers(r.Context(), f)
+		if err != nil {
+			writeSvcErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"by_reviewers": st})
+	default:
+		writeSvcErr(w, service.Invalid("by", "must be teams or reviewers"))
+	}
+}
+
+// statsSeries answers /stats/get with a bucket: assignment time series per
+// user or per team.
+func (h *Handlers) statsSeries(w http.ResponseWriter, r *http.Request, by, bucket string, page repo.Page) {
+	switch by {
+	case "users":
+		st, next, err := h.svc.SeriesByUsers(r.Context(), bucket, page)
+		if err != nil {
+			writeSvcErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"bucket": bucket, "series_by_users": st, "next_cursor": nextCursor(next)})
+	case "teams":
+		st, next, err := h.svc.SeriesByTeams(r.Context(), bucket, page)
 		if err != nil {
 			writeSvcErr(w, err)
 			return
 		}
-		writeJSON(w, 200, map[string]any{"by_prs": st})
+		writeJSON(w, 200, map[string]any{"bucket": bucket, "series_by_teams": st, "next_cursor": nextCursor(next)})
 	default:
-		writeErr(w, 400, "NOT_FOUND", "unknown by param")
+		writeSvcErr(w, service.Invalid("by", "must be users or teams with bucket"))
+	}
+}
+
+// -------- request / response helpers --------
+
+type validatable interface {
+	Validate() error
+}
+
+// decodeBody decodes and validates a JSON request body, writing the error
+// response itself when the body is rejected.
+func decodeBody(w http.ResponseWriter, r *http.Request, req validatable) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeSvcErr(w, service.Invalid("body", "malformed JSON: "+err.Error()))
+		return false
+	}
+	if err := req.Validate(); err != nil {
+		writeSvcErr(w, err)
+		return false
 	}
+	return true
 }
 
-// -------- error helpers --------
+func requireQuery(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		writeSvcErr(w, service.Invalid(name, "required"))
+		return "", false
+	}
+	return v, true
+}
 
 func writeSvcErr(w http.ResponseWriter, err error) {
-	code, msg, httpCode := service.ToHTTPError(err)
-	writeErr(w, httpCode, code, msg)
+	apiErr := service.ToHTTPError(err)
+	if apiErr.Status >= 500 {
+		log.Printf("internal error: %v", err)
+	}
+	writeErr(w, apiErr)
 }
 
-func writeErr(w http.ResponseWriter, httpCode int, code, msg string) {
-	writeJSON(w, httpCode, map[string]any{
-		"error": map[string]any{
-			"code":    code,
-			"message": msg,
-		},
-	})
+func writeErr(w http.ResponseWriter, apiErr service.APIError) {
+	writeJSON(w, apiErr.Status, map[string]any{"error": apiErr})
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
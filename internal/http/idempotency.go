@@ -0,0 +1,93 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"reviewer-service/internal/service"
+)
+
+const (
+	idempotencyHeader   = "Idempotency-Key"
+	idempotencyReplayed = "Idempotency-Replayed"
+	maxIdempotencyKey   = 255
+)
+
+// idempotency stores the first response of a POST request carrying an
+// Idempotency-Key header and replays it for retries with the same key and route.
+// streamingPaths ignore the header: hashing would buffer their whole body.
+func idempotency(svc *service.Service, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyHeader)
+			if r.Method != http.MethodPost || key == "" || streamingPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				writeSvcErr(w, service.Invalid(idempotencyHeader, "must be at most 255 characters"))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeSvcErr(w, service.Invalid("body", "cannot read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			hash := hex.EncodeToString(sum[:])
+			path := r.URL.Path
+
+			stored, err := svc.IdempotencyBegin(r.Context(), key, r.Method, path, hash, ttl)
+			if err != nil {
+				writeSvcErr(w, err)
+				return
+			}
+			if stored != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(idempotencyReplayed, "true")
+				w.WriteHeader(stored.StatusCode)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			// The request context may already be cancelled by the timeout
+			// middleware once the handler returns, the key must still be settled.
+			ctx := context.WithoutCancel(r.Context())
+			done := false
+			defer func() {
+				if !done {
+					_ = svc.IdempotencyRelease(ctx, key, r.Method, path)
+				}
+			}()
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= 500 {
+				return
+			}
+			resp := service.StoredResponse{StatusCode: status, Body: buf.Bytes()}
+			if err := svc.IdempotencyComplete(ctx, key, r.Method, path, resp); err != nil {
+				log.Printf("idempotency: store %q: %v", key, err)
+				return
+			}
+			done = true
+		})
+	}
+}
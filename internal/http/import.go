@@ -0,0 +1,171 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/service"
+)
+
+const maxImportBody = 10 << 20
+
+// importDoc is the YAML/JSON form of an org definition.
+type importDoc struct {
+	Teams []struct {
+		TeamName string `yaml:"team_name"`
+		Members  []struct {
+			UserID   string `yaml:"user_id"`
+			Username string `yaml:"username"`
+			IsActive *bool  `yaml:"is_active"`
+		} `yaml:"members"`
+	} `yaml:"teams"`
+}
+
+func (h *Handlers) AdminImport(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := parseBoolParam(r.URL.Query(), "dry_run")
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	teams, err := readImport(w, r)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	res, err := h.svc.Import(r.Context(), teams, dryRun)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, res)
+}
+
+func (h *Handlers) TeamSync(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = service.SyncModePlan
+	}
+	allTeams, err := parseBoolParam(q, "all_teams")
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	teams, err := readImport(w, r)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	res, err := h.svc.Sync(r.Context(), teams, mode, allTeams)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, res)
+}
+
+// readImport decodes an org definition given as YAML or JSON (see importDoc)
+// or as CSV with a team_name,user_id,username[,is_active] header.
+// is_active defaults to true.
+func readImport(w http.ResponseWriter, r *http.Request) ([]service.ImportTeam, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBody))
+	if err != nil {
+		return nil, service.Invalid("body", "cannot read request body: "+err.Error())
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt == "text/csv" {
+		return parseImportCSV(body)
+	}
+	return parseImportYAML(body)
+}
+
+func parseImportYAML(body []byte) ([]service.ImportTeam, error) {
+	var doc importDoc
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, service.Invalid("body", "malformed YAML: "+err.Error())
+	}
+
+	teams := make([]service.ImportTeam, 0, len(doc.Teams))
+	for _, t := range doc.Teams {
+		it := service.ImportTeam{TeamName: t.TeamName, Members: []models.TeamMember{}}
+		for _, m := range t.Members {
+			active := m.IsActive == nil || *m.IsActive
+			it.Members = append(it.Members, models.TeamMember{UserID: m.UserID, Username: m.Username, IsActive: active})
+		}
+		teams = append(teams, it)
+	}
+	return teams, nil
+}
+
+// parseImportCSV groups rows by team_name in order of first appearance. A row
+// with only team_name declares a team without members.
+func parseImportCSV(body []byte) ([]service.ImportTeam, error) {
+	cr := csv.NewReader(bytes.NewReader(body))
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, service.Invalid("body", "malformed CSV: "+err.Error())
+	}
+	if len(rows) == 0 {
+		return []service.ImportTeam{}, nil
+	}
+
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		col[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"team_name", "user_id", "username"} {
+		if _, ok := col[name]; !ok {
+			return nil, service.Invalid("body", "CSV header must contain team_name, user_id and username")
+		}
+	}
+	get := func(row []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var teams []service.ImportTeam
+	index := map[string]int{}
+	for n, row := range rows[1:] {
+		line := n + 2
+		team := get(row, "team_name")
+		if team == "" {
+			return nil, service.Invalid("body", fmt.Sprintf("line %d: team_name is required", line))
+		}
+		i, ok := index[team]
+		if !ok {
+			i = len(teams)
+			index[team] = i
+			teams = append(teams, service.ImportTeam{TeamName: team, Members: []models.TeamMember{}})
+		}
+
+		id, name := get(row, "user_id"), get(row, "username")
+		if id == "" && name == "" {
+			continue
+		}
+		active := true
+		if v := get(row, "is_active"); v != "" {
+			if active, err = strconv.ParseBool(v); err != nil {
+				return nil, service.Invalid("body", fmt.Sprintf("line %d: is_active must be true or false", line))
+			}
+		}
+		teams[i].Members = append(teams[i].Members, models.TeamMember{UserID: id, Username: name, IsActive: active})
+	}
+	return teams, nil
+}
@@ -0,0 +1,59 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"reviewer-service/internal/service"
+)
+
+const (
+	defaultJobRuns = 20
+	maxJobRuns     = 100
+)
+
+func (h *Handlers) AdminJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.jobs.Jobs(r.Context())
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{
+		"instance": h.jobs.Instance(),
+		"leader":   h.jobs.IsLeader(),
+		"jobs":     jobs,
+	})
+}
+
+func (h *Handlers) AdminJobRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := defaultJobRuns
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxJobRuns {
+			writeSvcErr(w, service.Invalid("limit", fmt.Sprintf("must be between 1 and %d", maxJobRuns)))
+			return
+		}
+		limit = n
+	}
+	runs, err := h.jobs.Runs(r.Context(), q.Get("name"), limit)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"runs": runs})
+}
+
+func (h *Handlers) AdminJobTrigger(w http.ResponseWriter, r *http.Request) {
+	var req AdminJobTriggerReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	run, err := h.jobs.Trigger(r.Context(), req.Name)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 202, map[string]any{"run": run})
+}
@@ -0,0 +1,179 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+
+	"reviewer-service/internal/service"
+)
+
+// LoadSpec reads and validates the OpenAPI document at path.
+func LoadSpec(ctx context.Context, path string) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// openapiValidation rejects requests that do not conform to doc; the bodies of
+// streamingPaths are left to their handlers, which read them as a stream. With
+// validateResponses set, responses are buffered and checked as well; a
+// non-conforming response is replaced by an INTERNAL error so that tests fail
+// loudly when the handlers and the spec drift apart.
+func openapiValidation(doc *openapi3.T, validateResponses bool) (func(http.Handler) http.Handler, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	opts := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	streamOpts := *opts
+	streamOpts.ExcludeRequestBody = true
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fail := errorWriter(r)
+			route, params, err := router.FindRoute(r)
+			switch {
+			case errors.Is(err, routers.ErrMethodNotAllowed):
+				fail(w, service.APIError{Code: "NOT_FOUND", Message: "method not allowed", Status: 405})
+				return
+			case err != nil:
+				fail(w, service.APIError{Code: "NOT_FOUND", Message: "unknown route", Status: 404})
+				return
+			}
+
+			if r.ContentLength != 0 && r.Header.Get("Content-Type") == "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			in := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: params,
+				Route:      route,
+				Options:    opts,
+			}
+			if streamingPaths[r.URL.Path] {
+				in.Options = &streamOpts
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), in); err != nil {
+				fail(w, service.ToHTTPError(&service.ValidationError{Fields: specFieldErrors(err, "body")}))
+				return
+			}
+
+			if !validateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &bufferedWriter{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			out := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: in,
+				Status:                 rec.status,
+				Header:                 rec.header,
+				Options:                opts,
+			}
+			out.SetBodyBytes(rec.body.Bytes())
+			if err := openapi3filter.ValidateResponse(r.Context(), out); err != nil {
+				log.Printf("openapi: %s %s: response does not match spec: %v", r.Method, r.URL.Path, err)
+				fail(w, service.APIError{
+					Code:    "INTERNAL",
+					Message: "response does not match openapi.yml: " + err.Error(),
+					Status:  500,
+				})
+				return
+			}
+			rec.flush(w)
+		})
+	}, nil
+}
+
+// specFieldErrors flattens kin-openapi validation errors into field errors.
+// field is the name reported when the error does not point at a narrower one.
+func specFieldErrors(err error, field string) []service.FieldError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var out []service.FieldError
+		for _, sub := range e {
+			out = append(out, specFieldErrors(sub, field)...)
+		}
+		return out
+	case *openapi3filter.RequestError:
+		if e.Parameter != nil {
+			field = e.Parameter.Name
+		}
+		switch e.Err.(type) {
+		case openapi3.MultiError, *openapi3.SchemaError:
+			return specFieldErrors(e.Err, field)
+		case nil:
+			return []service.FieldError{{Field: field, Reason: e.Reason}}
+		}
+		reason := e.Err.Error()
+		if e.Reason != "" && e.Reason != reason {
+			reason = e.Reason + ": " + reason
+		}
+		return []service.FieldError{{Field: field, Reason: reason}}
+	case *openapi3.SchemaError:
+		if p := e.JSONPointer(); len(p) > 0 && field == "body" {
+			field = pointerField(p)
+		}
+		return []service.FieldError{{Field: field, Reason: e.Reason}}
+	default:
+		return []service.FieldError{{Field: field, Reason: err.Error()}}
+	}
+}
+
+// pointerField renders a JSON pointer the way DTO validation names fields,
+// e.g. ["members", "0", "username"] becomes members[0].username.
+func pointerField(p []string) string {
+	var b strings.Builder
+	for _, seg := range p {
+		if _, err := strconv.Atoi(seg); err == nil {
+			b.WriteString("[" + seg + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+// bufferedWriter holds a response until it has been validated.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) Header() http.Header         { return b.header }
+func (b *bufferedWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedWriter) WriteHeader(status int)      { b.status = status }
+
+func (b *bufferedWriter) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(b.body.Bytes())
+}
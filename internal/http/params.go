@@ -0,0 +1,104 @@
+package httpx
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+// parsePage reads limit, cursor, sort, order, from and to query parameters.
+// The first of sorts is the default sort key; it is ordered descending unless
+// order=asc is given.
+func parsePage(q url.Values, sorts ...string) (repo.Page, error) {
+	var p repo.Page
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > repo.MaxPageLimit {
+			return p, service.Invalid("limit", fmt.Sprintf("must be between 1 and %d", repo.MaxPageLimit))
+		}
+		p.Limit = n
+	}
+	p.Cursor = q.Get("cursor")
+
+	p.Sort = sorts[0]
+	if v := q.Get("sort"); v != "" {
+		if !slices.Contains(sorts, v) {
+			return p, service.Invalid("sort", fmt.Sprintf("must be one of %v", sorts))
+		}
+		p.Sort = v
+	}
+
+	switch q.Get("order") {
+	case "", "desc":
+		p.Desc = true
+	case "asc":
+		p.Desc = false
+	default:
+		return p, service.Invalid("order", "must be asc or desc")
+	}
+
+	var err error
+	if p.From, err = parseTimeParam(q, "from"); err != nil {
+		return p, err
+	}
+	if p.To, err = parseTimeParam(q, "to"); err != nil {
+		return p, err
+	}
+	if p.From != nil && p.To != nil && !p.From.Before(*p.To) {
+		return p, service.Invalid("from", "must be before to")
+	}
+	return p, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or plain YYYY-MM-DD dates (UTC).
+func parseTimeParam(q url.Values, name string) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, v)
+	}
+	if err != nil {
+		return nil, service.Invalid(name, "must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+func parseStatusParam(q url.Values) (models.PRStatus, error) {
+	switch s := models.PRStatus(q.Get("status")); s {
+	case "", models.PROpen, models.PRMerged:
+		return s, nil
+	default:
+		return "", service.Invalid("status", "must be OPEN or MERGED")
+	}
+}
+
+func parseBoolParam(q url.Values, name string) (bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, service.Invalid(name, "must be true or false")
+	}
+	return b, nil
+}
+
+// nextCursor renders an empty cursor as JSON null.
+func nextCursor(c string) any {
+	if c == "" {
+		return nil
+	}
+	return c
+}
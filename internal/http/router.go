@@ -4,20 +4,49 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"reviewer-service/internal/scheduler"
 	"reviewer-service/internal/service"
 )
 
-func NewRouter(svc *service.Service) http.Handler {
-	h := &Handlers{svc: svc}
+type Options struct {
+	// IdempotencyTTL is how long responses stored for an Idempotency-Key are replayed.
+	IdempotencyTTL time.Duration
+	// Spec, when set, is used to validate every request (see LoadSpec).
+	Spec *openapi3.T
+	// ValidateResponses additionally checks responses against Spec. Meant for tests.
+	ValidateResponses bool
+	// GraphQL, when set, is mounted at POST /graphql.
+	GraphQL http.Handler
+	// SCIMToken, when set, is the bearer token required on /scim/v2.
+	SCIMToken string
+	// Jobs, when set, is exposed at /admin/jobs.
+	Jobs *scheduler.Scheduler
+}
+
+func NewRouter(svc *service.Service, opts Options) (http.Handler, error) {
+	h := &Handlers{svc: svc, jobs: opts.Jobs}
+
+	if opts.IdempotencyTTL <= 0 {
+		opts.IdempotencyTTL = 24 * time.Hour
+	}
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(5 * time.Second))
+	r.Use(timeoutExcept(5 * time.Second))
+	if opts.Spec != nil {
+		validate, err := openapiValidation(opts.Spec, opts.ValidateResponses)
+		if err != nil {
+			return nil, err
+		}
+		r.Use(validate)
+	}
+	r.Use(idempotency(svc, opts.IdempotencyTTL))
 
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, 200, map[string]any{"ok": true})
@@ -27,18 +56,60 @@ func NewRouter(svc *service.Service) http.Handler {
 	r.Post("/team/add", h.TeamAdd)
 	r.Get("/team/get", h.TeamGet)
 	r.Post("/team/deactivate", h.TeamDeactivate)
+	r.Post("/team/sync", h.TeamSync)
+	r.Get("/team/getSettings", h.TeamGetSettings)
+	r.Post("/team/setSettings", h.TeamSetSettings)
 
 	// Users
 	r.Post("/users/setIsActive", h.UserSetIsActive)
 	r.Get("/users/getReview", h.UserGetReview)
+	r.Get("/users/getSettings", h.UserGetSettings)
+	r.Post("/users/setSettings", h.UserSetSettings)
 
 	// PRs
 	r.Post("/pullRequest/create", h.PRCreate)
 	r.Post("/pullRequest/merge", h.PRMerge)
 	r.Post("/pullRequest/reassign", h.PRReassign)
+	r.Post("/pullRequest/review", h.PRReview)
+	r.Get("/pullRequest/get", h.PRGet)
+	r.Get("/pullRequest/list", h.PRList)
 
 	// Stats
 	r.Get("/stats/get", h.StatsGet)
+	r.Get("/stats/fairness", h.StatsFairness)
+	r.Get("/stats/latency", h.StatsLatency)
+
+	// Admin
+	r.Post("/admin/import", h.AdminImport)
+	r.Get("/admin/export", h.AdminExport)
+	r.Post("/admin/import-snapshot", h.AdminImportSnapshot)
+	if opts.Jobs != nil {
+		r.Get("/admin/jobs", h.AdminJobs)
+		r.Get("/admin/jobs/runs", h.AdminJobRuns)
+		r.Post("/admin/jobs/trigger", h.AdminJobTrigger)
+	}
+
+	// SCIM provisioning
+	r.Route(scimPrefix, func(r chi.Router) {
+		r.Use(scimAuth(opts.SCIMToken))
+		r.Get("/ServiceProviderConfig", h.SCIMServiceProviderConfig)
+		r.Get("/Users", h.SCIMListUsers)
+		r.Post("/Users", h.SCIMCreateUser)
+		r.Get("/Users/{id}", h.SCIMGetUser)
+		r.Put("/Users/{id}", h.SCIMReplaceUser)
+		r.Patch("/Users/{id}", h.SCIMPatchUser)
+		r.Delete("/Users/{id}", h.SCIMDeleteUser)
+		r.Get("/Groups", h.SCIMListGroups)
+		r.Post("/Groups", h.SCIMCreateGroup)
+		r.Get("/Groups/{id}", h.SCIMGetGroup)
+		r.Put("/Groups/{id}", h.SCIMReplaceGroup)
+		r.Patch("/Groups/{id}", h.SCIMPatchGroup)
+		r.Delete("/Groups/{id}", h.SCIMDeleteGroup)
+	})
+
+	if opts.GraphQL != nil {
+		r.Method(http.MethodPost, "/graphql", opts.GraphQL)
+	}
 
-	return r
+	return r, nil
 }
@@ -0,0 +1,680 @@
+package httpx
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/go-chi/chi/v5"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+// SCIM 2.0 (RFC 7643/7644) provisioning. Users map to users with
+// id = userName = user_id and displayName = username; groups map to teams
+// with id = displayName = team_name. A user belongs to at most one group.
+
+const (
+	scimPrefix      = "/scim/v2"
+	scimContentType = "application/scim+json"
+
+	scimUserSchema   = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema  = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema  = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	scimDefaultCount = 100
+)
+
+func init() {
+	openapi3filter.RegisterBodyDecoder(scimContentType, openapi3filter.JSONBodyDecoder)
+}
+
+type scimRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+type scimUser struct {
+	Schemas     []string  `json:"schemas"`
+	ID          string    `json:"id"`
+	UserName    string    `json:"userName"`
+	DisplayName string    `json:"displayName"`
+	Active      bool      `json:"active"`
+	Groups      []scimRef `json:"groups"`
+	Meta        scimMeta  `json:"meta"`
+}
+
+type scimGroup struct {
+	Schemas     []string  `json:"schemas"`
+	ID          string    `json:"id"`
+	DisplayName string    `json:"displayName"`
+	Members     []scimRef `json:"members"`
+	Meta        scimMeta  `json:"meta"`
+}
+
+type scimList[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+// scimUserIn is the writable part of a SCIM user.
+type scimUserIn struct {
+	UserName    string `json:"userName"`
+	DisplayName string `json:"displayName"`
+	Name        struct {
+		Formatted  string `json:"formatted"`
+		GivenName  string `json:"givenName"`
+		FamilyName string `json:"familyName"`
+	} `json:"name"`
+	Active *bool `json:"active"`
+}
+
+// username picks the display name, falling back to userName.
+func (u scimUserIn) username() string {
+	switch {
+	case u.DisplayName != "":
+		return u.DisplayName
+	case u.Name.Formatted != "":
+		return u.Name.Formatted
+	case u.Name.GivenName != "" || u.Name.FamilyName != "":
+		return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+	default:
+		return u.UserName
+	}
+}
+
+type scimGroupIn struct {
+	DisplayName string    `json:"displayName"`
+	Members     []scimRef `json:"members"`
+}
+
+type scimPatch struct {
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// scimError is a SCIM-specific failure with an explicit scimType.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimError) Error() string { return e.detail }
+
+func scimBadRequest(scimType, detail string) error {
+	return &scimError{status: 400, scimType: scimType, detail: detail}
+}
+
+// scimAuth requires "Authorization: Bearer <token>" when token is set.
+func scimAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != "" {
+				got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+				if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+					writeSCIMErr(w, &scimError{status: 401, detail: "missing or invalid bearer token"})
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// -------- Users --------
+
+func (h *Handlers) SCIMListUsers(w http.ResponseWriter, r *http.Request) {
+	id, filtered, err := scimFilter(r, "userName", "id")
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	start, count, err := scimPage(r)
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	var users []models.User
+	total := 0
+	if !filtered || id != "" {
+		users, total, err = h.svc.UserList(r.Context(), id, start-1, count)
+		if err != nil {
+			writeSCIMErr(w, err)
+			return
+		}
+	}
+	res := make([]scimUser, 0, len(users))
+	for _, u := range users {
+		res = append(res, toSCIMUser(u))
+	}
+	writeSCIM(w, 200, scimList[scimUser]{
+		Schemas: []string{scimListSchema}, TotalResults: total, StartIndex: start, ItemsPerPage: len(res), Resources: res,
+	})
+}
+
+func (h *Handlers) SCIMGetUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.svc.UserGet(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 200, toSCIMUser(u))
+}
+
+func (h *Handlers) SCIMCreateUser(w http.ResponseWriter, r *http.Request) {
+	var in scimUserIn
+	if err := readSCIM(r, &in); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	if in.UserName == "" {
+		writeSCIMErr(w, scimBadRequest("invalidValue", "userName is required"))
+		return
+	}
+	active := in.Active == nil || *in.Active
+	u, err := h.svc.UserCreate(r.Context(), in.UserName, in.username(), active)
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 201, toSCIMUser(u))
+}
+
+func (h *Handlers) SCIMReplaceUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in scimUserIn
+	if err := readSCIM(r, &in); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	if in.UserName != "" && in.UserName != id {
+		writeSCIMErr(w, scimBadRequest("mutability", "userName cannot be changed"))
+		return
+	}
+	name := in.username()
+	if name == "" {
+		name = id
+	}
+	active := in.Active == nil || *in.Active
+	u, err := h.svc.UserUpdate(r.Context(), id, service.UserPatch{Username: &name, IsActive: &active})
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 200, toSCIMUser(u))
+}
+
+// SCIMPatchUser supports active, displayName and userName (which must stay
+// the same); other attributes are accepted and ignored.
+func (h *Handlers) SCIMPatchUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in scimPatch
+	if err := readSCIM(r, &in); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+
+	var p service.UserPatch
+	set := func(attr string, raw json.RawMessage) error {
+		switch strings.ToLower(attr) {
+		case "active":
+			b, err := scimBool(raw)
+			if err != nil {
+				return err
+			}
+			p.IsActive = &b
+		case "displayname":
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return scimBadRequest("invalidValue", "displayName must be a string")
+			}
+			p.Username = &s
+		case "username":
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil || s != id {
+				return scimBadRequest("mutability", "userName cannot be changed")
+			}
+		}
+		return nil
+	}
+	for _, op := range in.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		default:
+			writeSCIMErr(w, scimBadRequest("invalidValue", "unsupported op "+strconv.Quote(op.Op)+" for users"))
+			return
+		}
+		if op.Path != "" {
+			if err := set(op.Path, op.Value); err != nil {
+				writeSCIMErr(w, err)
+				return
+			}
+			continue
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			writeSCIMErr(w, scimBadRequest("invalidValue", "value must be an object when path is omitted"))
+			return
+		}
+		for attr, v := range attrs {
+			if err := set(attr, v); err != nil {
+				writeSCIMErr(w, err)
+				return
+			}
+		}
+	}
+
+	u, err := h.svc.UserUpdate(r.Context(), id, p)
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 200, toSCIMUser(u))
+}
+
+// SCIMDeleteUser deactivates the user: users referenced by PRs are never
+// removed.
+func (h *Handlers) SCIMDeleteUser(w http.ResponseWriter, r *http.Request) {
+	inactive := false
+	if _, err := h.svc.UserUpdate(r.Context(), chi.URLParam(r, "id"), service.UserPatch{IsActive: &inactive}); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// -------- Groups --------
+
+func (h *Handlers) SCIMListGroups(w http.ResponseWriter, r *http.Request) {
+	name, filtered, err := scimFilter(r, "displayName", "id")
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	start, count, err := scimPage(r)
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	var teams []models.Team
+	total := 0
+	if !filtered || name != "" {
+		teams, total, err = h.svc.TeamList(r.Context(), name, start-1, count)
+		if err != nil {
+			writeSCIMErr(w, err)
+			return
+		}
+	}
+	res := make([]scimGroup, 0, len(teams))
+	for _, t := range teams {
+		res = append(res, toSCIMGroup(t))
+	}
+	writeSCIM(w, 200, scimList[scimGroup]{
+		Schemas: []string{scimListSchema}, TotalResults: total, StartIndex: start, ItemsPerPage: len(res), Resources: res,
+	})
+}
+
+func (h *Handlers) SCIMGetGroup(w http.ResponseWriter, r *http.Request) {
+	t, err := h.svc.TeamGet(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 200, toSCIMGroup(t))
+}
+
+func (h *Handlers) SCIMCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var in scimGroupIn
+	if err := readSCIM(r, &in); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	t, err := h.svc.TeamCreate(r.Context(), in.DisplayName, refValues(in.Members))
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 201, toSCIMGroup(t))
+}
+
+func (h *Handlers) SCIMReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in scimGroupIn
+	if err := readSCIM(r, &in); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	if in.DisplayName != "" && in.DisplayName != id {
+		writeSCIMErr(w, scimBadRequest("mutability", "displayName cannot be changed"))
+		return
+	}
+	t, err := h.svc.TeamUpdateMembers(r.Context(), id, refValues(in.Members), nil, true)
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 200, toSCIMGroup(t))
+}
+
+var scimMemberPath = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"((?:[^"\\]|\\.)*)"\s*\]$`)
+
+// SCIMPatchGroup supports add, remove and replace of members, including the
+// members[value eq "id"] path form. All operations apply in one transaction.
+func (h *Handlers) SCIMPatchGroup(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in scimPatch
+	if err := readSCIM(r, &in); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+
+	// Fold the operations into the final add/remove sets, later ones win.
+	add := map[string]bool{}
+	remove := map[string]bool{}
+	replace := false
+	for _, op := range in.Operations {
+		var ids []string
+		path := strings.TrimSpace(op.Path)
+		switch m := scimMemberPath.FindStringSubmatch(path); {
+		case m != nil:
+			v, err := strconv.Unquote(`"` + m[1] + `"`)
+			if err != nil {
+				writeSCIMErr(w, scimBadRequest("invalidPath", "malformed path "+strconv.Quote(op.Path)))
+				return
+			}
+			ids = []string{v}
+		case strings.EqualFold(path, "members"):
+			if len(op.Value) > 0 {
+				var refs []scimRef
+				if err := json.Unmarshal(op.Value, &refs); err != nil {
+					writeSCIMErr(w, scimBadRequest("invalidValue", "members must be an array of {value}"))
+					return
+				}
+				ids = refValues(refs)
+			}
+		case path == "":
+			var attrs scimGroupIn
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				writeSCIMErr(w, scimBadRequest("invalidValue", "value must be an object when path is omitted"))
+				return
+			}
+			if attrs.DisplayName != "" && attrs.DisplayName != id {
+				writeSCIMErr(w, scimBadRequest("mutability", "displayName cannot be changed"))
+				return
+			}
+			if attrs.Members == nil {
+				continue
+			}
+			ids = refValues(attrs.Members)
+		case strings.EqualFold(path, "displayName"):
+			var name string
+			if err := json.Unmarshal(op.Value, &name); err != nil || name != id {
+				writeSCIMErr(w, scimBadRequest("mutability", "displayName cannot be changed"))
+				return
+			}
+			continue
+		default:
+			writeSCIMErr(w, scimBadRequest("invalidPath", "unsupported path "+strconv.Quote(op.Path)))
+			return
+		}
+
+		switch strings.ToLower(op.Op) {
+		case "add":
+			for _, v := range ids {
+				add[v] = true
+				delete(remove, v)
+			}
+		case "remove":
+			if path != "" && len(ids) == 0 {
+				// remove with path "members" and no value clears the group.
+				replace = true
+				clear(add)
+				clear(remove)
+				continue
+			}
+			for _, v := range ids {
+				remove[v] = true
+				delete(add, v)
+			}
+		case "replace":
+			replace = true
+			clear(add)
+			clear(remove)
+			for _, v := range ids {
+				add[v] = true
+			}
+		default:
+			writeSCIMErr(w, scimBadRequest("invalidValue", "unsupported op "+strconv.Quote(op.Op)))
+			return
+		}
+	}
+
+	t, err := h.svc.TeamUpdateMembers(r.Context(), id, setKeys(add), setKeys(remove), replace)
+	if err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	writeSCIM(w, 200, toSCIMGroup(t))
+}
+
+func (h *Handlers) SCIMDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.TeamDelete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeSCIMErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// -------- Discovery --------
+
+func (h *Handlers) SCIMServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(ok bool) map[string]any { return map[string]any{"supported": ok} }
+	writeSCIM(w, 200, map[string]any{
+		"schemas":        []string{scimConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": repo.MaxPageLimit},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Authorization: Bearer <SCIM_TOKEN>",
+		}},
+	})
+}
+
+// -------- helpers --------
+
+func toSCIMUser(u models.User) scimUser {
+	groups := []scimRef{}
+	if u.TeamName != "" {
+		groups = append(groups, scimRef{Value: u.TeamName, Display: u.TeamName})
+	}
+	return scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          u.UserID,
+		UserName:    u.UserID,
+		DisplayName: u.Username,
+		Active:      u.IsActive,
+		Groups:      groups,
+		Meta:        scimMeta{ResourceType: "User", Location: scimPrefix + "/Users/" + u.UserID},
+	}
+}
+
+func toSCIMGroup(t models.Team) scimGroup {
+	members := make([]scimRef, 0, len(t.Members))
+	for _, m := range t.Members {
+		members = append(members, scimRef{Value: m.UserID, Display: m.Username})
+	}
+	return scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          t.TeamName,
+		DisplayName: t.TeamName,
+		Members:     members,
+		Meta:        scimMeta{ResourceType: "Group", Location: scimPrefix + "/Groups/" + t.TeamName},
+	}
+}
+
+func refValues(refs []scimRef) []string {
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		ids = append(ids, ref.Value)
+	}
+	return ids
+}
+
+func setKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// scimBool accepts JSON booleans and the "True"/"False" strings some
+// identity providers send.
+func scimBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, scimBadRequest("invalidValue", "active must be a boolean")
+}
+
+var scimEqFilter = regexp.MustCompile(`^\s*(\w+)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimFilter parses the filter parameter. Only `<attr> eq "value"` on one of
+// attrs is supported; filtered is false when there is no filter.
+func scimFilter(r *http.Request, attrs ...string) (value string, filtered bool, err error) {
+	f := r.URL.Query().Get("filter")
+	if f == "" {
+		return "", false, nil
+	}
+	if m := scimEqFilter.FindStringSubmatch(f); m != nil {
+		for _, a := range attrs {
+			if !strings.EqualFold(m[1], a) {
+				continue
+			}
+			if v, err := strconv.Unquote(`"` + m[2] + `"`); err == nil {
+				return v, true, nil
+			}
+		}
+	}
+	return "", false, scimBadRequest("invalidFilter", `only `+strings.Join(attrs, " or ")+` eq "value" filters are supported`)
+}
+
+// scimPage reads the 1-based startIndex and count parameters.
+func scimPage(r *http.Request) (start, count int, err error) {
+	q := r.URL.Query()
+	start, count = 1, scimDefaultCount
+	if v := q.Get("startIndex"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil {
+			return 0, 0, scimBadRequest("invalidValue", "startIndex must be an integer")
+		}
+		start = max(start, 1)
+	}
+	if v := q.Get("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil {
+			return 0, 0, scimBadRequest("invalidValue", "count must be an integer")
+		}
+		count = min(max(count, 0), repo.MaxPageLimit)
+	}
+	return start, count, nil
+}
+
+func readSCIM(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return scimBadRequest("invalidSyntax", "malformed JSON: "+err.Error())
+	}
+	return nil
+}
+
+func writeSCIM(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeSCIMErr(w http.ResponseWriter, err error) {
+	var se *scimError
+	if errors.As(err, &se) {
+		writeSCIMError(w, se.status, se.scimType, se.detail)
+		return
+	}
+	apiErr := service.ToHTTPError(err)
+	if apiErr.Status >= 500 {
+		log.Printf("internal error: %v", err)
+	}
+	writeSCIMAPIErr(w, apiErr)
+}
+
+// writeSCIMAPIErr renders a service error in the SCIM error format.
+func writeSCIMAPIErr(w http.ResponseWriter, apiErr service.APIError) {
+	status, scimType, detail := apiErr.Status, "", apiErr.Message
+	switch apiErr.Code {
+	case "VALIDATION_ERROR":
+		scimType = "invalidValue"
+		parts := make([]string, 0, len(apiErr.Details))
+		for _, f := range apiErr.Details {
+			parts = append(parts, f.Field+": "+f.Reason)
+		}
+		if len(parts) > 0 {
+			detail = strings.Join(parts, "; ")
+		}
+	case "TEAM_EXISTS", "USER_EXISTS":
+		status, scimType = 409, "uniqueness"
+	}
+	writeSCIMError(w, status, scimType, detail)
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]any{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeSCIM(w, status, body)
+}
+
+// errorWriter picks the error format for r: SCIM clients expect RFC 7644
+// errors, everything else gets the usual ErrorResponse.
+func errorWriter(r *http.Request) func(http.ResponseWriter, service.APIError) {
+	if strings.HasPrefix(r.URL.Path, scimPrefix+"/") {
+		return writeSCIMAPIErr
+	}
+	return writeErr
+}
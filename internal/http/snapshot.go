@@ -0,0 +1,80 @@
+package httpx
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// streamingPaths are exempt from the request timeout; their handlers also
+// lift the server read/write deadlines.
+var streamingPaths = map[string]bool{
+	"/admin/export":          true,
+	"/admin/import-snapshot": true,
+}
+
+func (h *Handlers) AdminExport(w http.ResponseWriter, r *http.Request) {
+	noDeadlines(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="reviewer-snapshot-`+time.Now().UTC().Format("20060102T150405Z")+`.ndjson"`)
+
+	cw := &countingWriter{w: w}
+	if _, err := h.svc.Export(r.Context(), cw); err != nil {
+		if cw.n == 0 {
+			w.Header().Del("Content-Disposition")
+			writeSvcErr(w, err)
+			return
+		}
+		// The status is gone already; abort the connection so the client
+		// does not mistake the partial body (which has no footer) for a
+		// complete snapshot.
+		log.Printf("export aborted after %d bytes: %v", cw.n, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h *Handlers) AdminImportSnapshot(w http.ResponseWriter, r *http.Request) {
+	noDeadlines(w)
+	counts, err := h.svc.RestoreSnapshot(r.Context(), r.Body)
+	if err != nil {
+		writeSvcErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"restored": counts})
+}
+
+// noDeadlines lifts the server read/write timeouts for long transfers.
+func noDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
+
+// timeoutExcept is middleware.Timeout that leaves streamingPaths alone.
+func timeoutExcept(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		timed := middleware.Timeout(d)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if streamingPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timed.ServeHTTP(w, r)
+		})
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type TeamMember struct {
 	UserID   string `json:"user_id"`
@@ -13,6 +16,52 @@ type Team struct {
 	Members  []TeamMember `json:"members"`
 }
 
+// AssignmentStrategy is how a team's reviewers are picked.
+type AssignmentStrategy string
+
+const (
+	// StrategyRandom picks at random, weighted by review_weight.
+	StrategyRandom AssignmentStrategy = "RANDOM"
+	// StrategyRoundRobin takes members in turn, in user_id order.
+	StrategyRoundRobin AssignmentStrategy = "ROUND_ROBIN"
+)
+
+// ReviewerRule requires at least one reviewer of the given level or with the
+// given skill; exactly one of the two is set. With IfLabel the rule only
+// applies to PRs carrying that label.
+type ReviewerRule struct {
+	Level   string `json:"level,omitempty"`
+	Skill   string `json:"skill,omitempty"`
+	IfLabel string `json:"if_label,omitempty"`
+}
+
+func (r ReviewerRule) String() string {
+	s := "skill=" + r.Skill
+	if r.Level != "" {
+		s = "level=" + r.Level
+	}
+	if r.IfLabel != "" {
+		s += " if label " + r.IfLabel
+	}
+	return s
+}
+
+// TeamSettings are per-team options; a nil field means the feature is off.
+// RotationCursor is the member picked last by round-robin; it cannot be set.
+// PairAvoidancePRs is how many of the author's latest PRs are checked for
+// reviewers to avoid. PreferWorkingHours favors reviewers within their working
+// hours.
+type TeamSettings struct {
+	TeamName             string             `json:"team_name"`
+	ReviewSLASeconds     *int               `json:"review_sla_seconds"`
+	EscalateAfterSeconds *int               `json:"escalate_after_seconds"`
+	AssignmentStrategy   AssignmentStrategy `json:"assignment_strategy"`
+	RotationCursor       *string            `json:"rotation_cursor"`
+	ReviewerRules        []ReviewerRule     `json:"reviewer_rules"`
+	PairAvoidancePRs     *int               `json:"pair_avoidance_prs"`
+	PreferWorkingHours   bool               `json:"prefer_working_hours"`
+}
+
 type User struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
@@ -20,6 +69,24 @@ type User struct {
 	IsActive bool   `json:"is_active"`
 }
 
+// UserSettings are per-user reviewer options; a nil field means no limit.
+// ReviewWeight is the user's relative chance of being picked as a reviewer,
+// 1 by default and 0 for never. Level and Skills are matched by the team's
+// reviewer rules. The user never reviews PRs by BlockedAuthors or with any of
+// BlockedLabels. WorkStart and WorkEnd are "HH:MM" in Timezone, UTC when nil.
+type UserSettings struct {
+	UserID         string   `json:"user_id"`
+	MaxOpenReviews *int     `json:"max_open_reviews"`
+	ReviewWeight   int      `json:"review_weight"`
+	Level          *string  `json:"level"`
+	Skills         []string `json:"skills"`
+	BlockedAuthors []string `json:"blocked_authors"`
+	BlockedLabels  []string `json:"blocked_labels"`
+	Timezone       *string  `json:"timezone"`
+	WorkStart      *string  `json:"work_start"`
+	WorkEnd        *string  `json:"work_end"`
+}
+
 type PRStatus string
 
 const (
@@ -33,6 +100,7 @@ type PullRequest struct {
 	AuthorID          string     `json:"author_id"`
 	Status            PRStatus   `json:"status"`
 	AssignedReviewers []string   `json:"assigned_reviewers"`
+	Labels            []string   `json:"labels,omitempty"`
 	CreatedAt         time.Time  `json:"createdAt"`
 	MergedAt          *time.Time `json:"mergedAt,omitempty"`
 }
@@ -43,3 +111,26 @@ type PullRequestShort struct {
 	AuthorID        string   `json:"author_id"`
 	Status          PRStatus `json:"status"`
 }
+
+const (
+	JobRunning   = "RUNNING"
+	JobSucceeded = "SUCCEEDED"
+	JobFailed    = "FAILED"
+
+	JobTriggerSchedule = "SCHEDULE"
+	JobTriggerManual   = "MANUAL"
+)
+
+// JobRun is one run of a background job. Instance names the server that ran
+// it; Result is whatever the job reported and Error is set when it failed.
+type JobRun struct {
+	ID         int64           `json:"id"`
+	Job        string          `json:"job"`
+	Trigger    string          `json:"trigger"`
+	Instance   string          `json:"instance"`
+	Status     string          `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+	Result     json.RawMessage `json:"result"`
+	Error      *string         `json:"error"`
+}
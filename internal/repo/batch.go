@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"reviewer-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Batch lookups keyed by many ids at once (GraphQL loaders, bulk imports).
+// They never fail for unknown ids: missing rows are simply absent from the result.
+
+func (r *Repo) ListTeamNames(ctx context.Context) ([]string, error) {
+	return r.listTeamNames(ctx, r.pool)
+}
+
+func (r *Repo) listTeamNames(ctx context.Context, q querier) ([]string, error) {
+	rows, err := q.Query(ctx, `SELECT team_name FROM teams ORDER BY team_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res = append(res, name)
+	}
+	return res, rows.Err()
+}
+
+func (r *Repo) ListUsersByIDs(ctx context.Context, ids []string) ([]models.User, error) {
+	return r.listUsers(ctx, r.pool, `user_id = ANY($1) ORDER BY user_id`, ids)
+}
+
+func (r *Repo) ListUsersByIDsTx(ctx context.Context, tx pgx.Tx, ids []string) ([]models.User, error) {
+	return r.listUsers(ctx, tx, `user_id = ANY($1) ORDER BY user_id`, ids)
+}
+
+func (r *Repo) ListUsersByTeams(ctx context.Context, teams []string) ([]models.User, error) {
+	return r.listUsers(ctx, r.pool, `team_name = ANY($1) ORDER BY user_id`, teams)
+}
+
+// LockUsersTx returns the existing users among ids plus every member of
+// teams, locked for update. Rows are locked in user_id order so concurrent
+// bulk updates do not deadlock.
+func (r *Repo) LockUsersTx(ctx context.Context, tx pgx.Tx, ids, teams []string) ([]models.User, error) {
+	return r.listUsers(ctx, tx, `user_id = ANY($1) OR team_name = ANY($2) ORDER BY user_id FOR UPDATE`, ids, teams)
+}
+
+// LockTeamsTx locks the rows of teams in team_name order until tx ends.
+// Paths that lock team members or select reviewers take their teams first,
+// so team rows always come before member rows. FOR NO KEY UPDATE leaves
+// members free to join or leave the team meanwhile.
+func (r *Repo) LockTeamsTx(ctx context.Context, tx pgx.Tx, teams []string) error {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM teams WHERE team_name = ANY($1) ORDER BY team_name FOR NO KEY UPDATE`, teams)
+	return err
+}
+
+func (r *Repo) ListTeamNamesTx(ctx context.Context, tx pgx.Tx) ([]string, error) {
+	return r.listTeamNames(ctx, tx)
+}
+
+// ExistingTeamsTx returns which of names already exist.
+func (r *Repo) ExistingTeamsTx(ctx context.Context, tx pgx.Tx, names []string) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, `SELECT team_name FROM teams WHERE team_name = ANY($1)`, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res[name] = true
+	}
+	return res, rows.Err()
+}
+
+func (r *Repo) listUsers(ctx context.Context, q querier, where string, args ...any) ([]models.User, error) {
+	rows, err := q.Query(ctx, `
+		SELECT user_id, username, COALESCE(team_name,''), is_active
+		FROM users WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive); err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, rows.Err()
+}
+
+func (r *Repo) ListPRsByIDs(ctx context.Context, ids []string) ([]models.PullRequest, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT pull_request_id, pull_request_name, author_id, status, labels, created_at, merged_at
+		FROM prs WHERE pull_request_id = ANY($1)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.PullRequest
+	for rows.Next() {
+		var pr models.PullRequest
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.Labels, &pr.CreatedAt, &pr.MergedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, pr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, r.fillReviewers(ctx, res)
+}
+
+// ListPRsByReviewers returns, per reviewer, the newest limit pull requests the
+// reviewer is assigned to, optionally filtered by status.
+func (r *Repo) ListPRsByReviewers(ctx context.Context, reviewerIDs []string, status models.PRStatus, limit int) (map[string][]models.PullRequest, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT reviewer, pull_request_id, pull_request_name, author_id, status, labels, created_at, merged_at
+		FROM (
+			SELECT prr.user_id AS reviewer, p.*,
+				ROW_NUMBER() OVER (PARTITION BY prr.user_id ORDER BY p.created_at DESC, p.pull_request_id DESC) AS rn
+			FROM pr_reviewers prr
+			JOIN prs p ON p.pull_request_id=prr.pull_request_id
+			WHERE prr.user_id = ANY($1) AND ($2::text = '' OR p.status=$2)
+		) s
+		WHERE rn <= $3
+		ORDER BY reviewer, rn
+	`, reviewerIDs, string(status), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type row struct {
+		reviewer string
+		pr       models.PullRequest
+	}
+	var all []row
+	for rows.Next() {
+		var x row
+		if err := rows.Scan(&x.reviewer, &x.pr.PullRequestID, &x.pr.PullRequestName, &x.pr.AuthorID, &x.pr.Status, &x.pr.Labels, &x.pr.CreatedAt, &x.pr.MergedAt); err != nil {
+			return nil, err
+		}
+		all = append(all, x)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prs := make([]models.PullRequest, len(all))
+	for i := range all {
+		prs[i] = all[i].pr
+	}
+	if err := r.fillReviewers(ctx, prs); err != nil {
+		return nil, err
+	}
+	res := map[string][]models.PullRequest{}
+	for i := range all {
+		res[all[i].reviewer] = append(res[all[i].reviewer], prs[i])
+	}
+	return res, nil
+}
+
+// AssignmentCountsByUsers counts review_assignments rows per user within
+// [from, to); either bound may be nil.
+func (r *Repo) AssignmentCountsByUsers(ctx context.Context, ids []string, from, to *time.Time) (map[string]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT assigned_user_id, COUNT(*)::bigint
+		FROM review_assignments
+		WHERE assigned_user_id = ANY($1)
+			AND ($2::timestamp IS NULL OR created_at >= $2)
+			AND ($3::timestamp IS NULL OR created_at < $3)
+		GROUP BY assigned_user_id
+	`, ids, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string]int64{}
+	for rows.Next() {
+		var id string
+		var n int64
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		res[id] = n
+	}
+	return res, rows.Err()
+}
+
+// fillReviewers loads AssignedReviewers for prs with a single query.
+func (r *Repo) fillReviewers(ctx context.Context, prs []models.PullRequest) error {
+	if len(prs) == 0 {
+		return nil
+	}
+	ids := make([]string, len(prs))
+	for i := range prs {
+		ids[i] = prs[i].PullRequestID
+	}
+	revs, err := r.ListReviewerIDsByPRs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range prs {
+		prs[i].AssignedReviewers = revs[prs[i].PullRequestID]
+		if prs[i].AssignedReviewers == nil {
+			prs[i].AssignedReviewers = []string{}
+		}
+	}
+	return nil
+}
@@ -54,11 +54,12 @@ type AffectedPR struct {
 	PRID   string
 	OldUID string
 	Author string
+	Labels []string
 }
 
 func (r *Repo) FindAffectedOpenPRsTx(ctx context.Context, tx pgx.Tx, deactivated []string) ([]AffectedPR, error) {
 	rows, err := tx.Query(ctx, `
-		SELECT prr.pull_request_id, prr.user_id, p.author_id
+		SELECT prr.pull_request_id, prr.user_id, p.author_id, p.labels
 		FROM pr_reviewers prr
 		JOIN prs p ON p.pull_request_id=prr.pull_request_id
 		WHERE p.status='OPEN' AND prr.user_id = ANY($1)
@@ -71,7 +72,7 @@ func (r *Repo) FindAffectedOpenPRsTx(ctx context.Context, tx pgx.Tx, deactivated
 	var res []AffectedPR
 	for rows.Next() {
 		var a AffectedPR
-		if err := rows.Scan(&a.PRID, &a.OldUID, &a.Author); err != nil {
+		if err := rows.Scan(&a.PRID, &a.OldUID, &a.Author, &a.Labels); err != nil {
 			return nil, err
 		}
 		res = append(res, a)
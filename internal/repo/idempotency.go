@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type IdempotencyRecord struct {
+	RequestHash string
+	StatusCode  *int
+	Body        []byte
+}
+
+// ReserveIdempotencyKey claims the key for a new request. It returns false when
+// a live (not yet expired) record for the same key and route already exists.
+func (r *Repo) ReserveIdempotencyKey(ctx context.Context, key, method, path, hash string, ttl time.Duration) (bool, error) {
+	var k string
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO idempotency_keys(idem_key, method, path, request_hash, expires_at)
+		VALUES($1,$2,$3,$4, now() + make_interval(secs => $5))
+		ON CONFLICT(idem_key, method, path) DO UPDATE SET
+			request_hash=EXCLUDED.request_hash,
+			status_code=NULL,
+			response_body=NULL,
+			created_at=now(),
+			expires_at=EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < now()
+		RETURNING idem_key
+	`, key, method, path, hash, ttl.Seconds()).Scan(&k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *Repo) GetIdempotencyRecord(ctx context.Context, key, method, path string) (IdempotencyRecord, error) {
+	var rec IdempotencyRecord
+	err := r.pool.QueryRow(ctx, `
+		SELECT request_hash, status_code, response_body
+		FROM idempotency_keys
+		WHERE idem_key=$1 AND method=$2 AND path=$3
+	`, key, method, path).Scan(&rec.RequestHash, &rec.StatusCode, &rec.Body)
+	return rec, err
+}
+
+func (r *Repo) CompleteIdempotencyKey(ctx context.Context, key, method, path string, status int, body []byte) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE idempotency_keys SET status_code=$4, response_body=$5
+		WHERE idem_key=$1 AND method=$2 AND path=$3
+	`, key, method, path, status, body)
+	return err
+}
+
+func (r *Repo) ReleaseIdempotencyKey(ctx context.Context, key, method, path string) error {
+	_, err := r.pool.Exec(ctx, `
+		DELETE FROM idempotency_keys WHERE idem_key=$1 AND method=$2 AND path=$3
+	`, key, method, path)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys deletes up to limit expired keys and returns
+// how many it deleted.
+func (r *Repo) DeleteExpiredIdempotencyKeys(ctx context.Context, limit int) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE ctid IN (SELECT ctid FROM idempotency_keys WHERE expires_at < now() LIMIT $1)
+	`, limit)
+	return ct.RowsAffected(), err
+}
@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"reviewer-service/internal/models"
+)
+
+// Lock is a session-level advisory lock. It holds on to its connection, and
+// Postgres drops the lock when that connection goes away.
+type Lock struct {
+	conn *pgxpool.Conn
+	key  string
+}
+
+// TryLock takes the advisory lock named key without waiting. It returns nil
+// when another session holds it.
+func (r *Repo) TryLock(ctx context.Context, key string) (*Lock, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtextextended($1, 0))`, key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if !ok {
+		conn.Release()
+		return nil, nil
+	}
+	return &Lock{conn: conn, key: key}, nil
+}
+
+// Check reports an error when the lock's connection is gone, and with it the
+// lock.
+func (l *Lock) Check(ctx context.Context) error {
+	return l.conn.Ping(ctx)
+}
+
+// Release unlocks and returns the connection to the pool. A connection that
+// cannot be unlocked is closed instead, which releases the lock too.
+func (l *Lock) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := l.conn.Exec(ctx, `SELECT pg_advisory_unlock(hashtextextended($1, 0))`, l.key); err != nil {
+		_ = l.conn.Hijack().Close(ctx)
+		return
+	}
+	l.conn.Release()
+}
+
+const jobRunCols = `id, job, trigger, instance, status, started_at, finished_at, result, error`
+
+func scanJobRun(row pgx.Row) (models.JobRun, error) {
+	var v models.JobRun
+	err := row.Scan(&v.ID, &v.Job, &v.Trigger, &v.Instance, &v.Status, &v.StartedAt, &v.FinishedAt, &v.Result, &v.Error)
+	return v, err
+}
+
+// StartJobRun records a new RUNNING run of job. The caller holds the job's
+// lock, so earlier runs still marked RUNNING were interrupted; they are
+// marked FAILED.
+func (r *Repo) StartJobRun(ctx context.Context, job, trigger, instance string) (models.JobRun, error) {
+	return scanJobRun(r.pool.QueryRow(ctx, `
+		WITH interrupted AS (
+			UPDATE job_runs SET status='FAILED', finished_at=now(), error='interrupted'
+			WHERE job=$1 AND status='RUNNING'
+		)
+		INSERT INTO job_runs(job, trigger, instance) VALUES ($1, $2, $3)
+		RETURNING `+jobRunCols, job, trigger, instance))
+}
+
+// FinishJobRun stores the outcome of a run; result is JSON or nil.
+func (r *Repo) FinishJobRun(ctx context.Context, id int64, status string, result []byte, errMsg *string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE job_runs SET status=$2, finished_at=now(), result=$3, error=$4
+		WHERE id=$1
+	`, id, status, result, errMsg)
+	return err
+}
+
+// PruneJobRuns deletes all but the keep latest runs of job.
+func (r *Repo) PruneJobRuns(ctx context.Context, job string, keep int) error {
+	_, err := r.pool.Exec(ctx, `
+		DELETE FROM job_runs
+		WHERE job=$1 AND id < (SELECT id FROM job_runs WHERE job=$1 ORDER BY id DESC OFFSET $2 LIMIT 1)
+	`, job, keep-1)
+	return err
+}
+
+// DueJobs returns the jobs among names that have not started a run within
+// their interval (in seconds, at the same index) as of the database clock.
+func (r *Repo) DueJobs(ctx context.Context, names []string, intervals []int) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT j.name
+		FROM unnest($1::text[], $2::int[]) AS j(name, secs)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM job_runs jr
+			WHERE jr.job = j.name AND jr.started_at > now() - make_interval(secs => j.secs)
+		)
+	`, names, intervals)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// LastJobRuns returns the latest run of every job that ever ran.
+func (r *Repo) LastJobRuns(ctx context.Context) (map[string]models.JobRun, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT DISTINCT ON (job) `+jobRunCols+`
+		FROM job_runs ORDER BY job, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]models.JobRun{}
+	for rows.Next() {
+		v, err := scanJobRun(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		res[v.Job] = v
+	}
+	return res, rows.Err()
+}
+
+// ListJobRuns returns the latest runs, newest first, of job or of all jobs
+// when job is empty.
+func (r *Repo) ListJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+jobRunCols+` FROM job_runs
+		WHERE $1 = '' OR job = $1
+		ORDER BY id DESC LIMIT $2
+	`, job, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := []models.JobRun{}
+	for rows.Next() {
+		v, err := scanJobRun(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Latency summarizes durations in seconds. Mean and the percentiles are nil
+// when Count is zero.
+type Latency struct {
+	Count int64    `json:"count"`
+	Mean  *float64 `json:"mean"`
+	P50   *float64 `json:"p50"`
+	P90   *float64 `json:"p90"`
+	P95   *float64 `json:"p95"`
+}
+
+// TeamLatency covers the PRs authored by a team's current members.
+type TeamLatency struct {
+	TeamName          string  `json:"team_name"`
+	PRs               int64   `json:"prs"`
+	TimeToFirstReview Latency `json:"time_to_first_review"`
+	TimeToMerge       Latency `json:"time_to_merge"`
+}
+
+// ReviewerLatency covers a reviewer's current assignments. Pending counts the
+// ones on open PRs that are not reviewed yet.
+type ReviewerLatency struct {
+	UserID       string  `json:"user_id"`
+	TeamName     string  `json:"team_name"`
+	Assigned     int64   `json:"assigned"`
+	Pending      int64   `json:"pending"`
+	TimeToReview Latency `json:"time_to_review"`
+}
+
+// LatencyFilter narrows latency stats to a team and a window. The window
+// bounds PR creation for teams and assignment time for reviewers.
+type LatencyFilter struct {
+	TeamName string
+	From     *time.Time
+	To       *time.Time
+}
+
+// LatencyByTeams reports time to first review (the earliest review of any
+// reviewer) and time to merge, both from PR creation.
+func (r *Repo) LatencyByTeams(ctx context.Context, f LatencyFilter) ([]TeamLatency, error) {
+	var a args
+	where := f.where(&a, "p.created_at")
+	rows, err := r.pool.Query(ctx, `
+		SELECT team_name, COUNT(*)::bigint,
+		       COUNT(ttfr), AVG(ttfr), percentile_cont(ARRAY[0.5, 0.9, 0.95]) WITHIN GROUP (ORDER BY ttfr),
+		       COUNT(ttm), AVG(ttm), percentile_cont(ARRAY[0.5, 0.9, 0.95]) WITHIN GROUP (ORDER BY ttm)
+		FROM (
+			SELECT u.team_name,
+			       EXTRACT(EPOCH FROM (
+			           SELECT MIN(prr.reviewed_at) FROM pr_reviewers prr WHERE prr.pull_request_id = p.pull_request_id
+			       ) - p.created_at)::float8 AS ttfr,
+			       EXTRACT(EPOCH FROM p.merged_at - p.created_at)::float8 AS ttm
+			FROM prs p
+			JOIN users u ON u.user_id = p.author_id
+			WHERE `+where+`
+		) d
+		GROUP BY team_name
+		ORDER BY team_name
+	`, a...)
+	if err != nil {
+		return nil, err
+	}
+	res := []TeamLatency{}
+	var v TeamLatency
+	var ttfr, ttm []float64
+	_, err = pgx.ForEachRow(rows, []any{
+		&v.TeamName, &v.PRs,
+		&v.TimeToFirstReview.Count, &v.TimeToFirstReview.Mean, &ttfr,
+		&v.TimeToMerge.Count, &v.TimeToMerge.Mean, &ttm,
+	}, func() error {
+		v.TimeToFirstReview.setPercentiles(ttfr)
+		v.TimeToMerge.setPercentiles(ttm)
+		res = append(res, v)
+		v = TeamLatency{} // pgx scans into the Mean pointers when they are set
+		return nil
+	})
+	return res, err
+}
+
+// LatencyByReviewers reports, per reviewer, the time from assignment to
+// their review.
+func (r *Repo) LatencyByReviewers(ctx context.Context, f LatencyFilter) ([]ReviewerLatency, error) {
+	var a args
+	where := f.where(&a, "prr.assigned_at")
+	rows, err := r.pool.Query(ctx, `
+		SELECT user_id, team_name, COUNT(*)::bigint,
+		       COUNT(*) FILTER (WHERE pending)::bigint,
+		       COUNT(ttr), AVG(ttr), percentile_cont(ARRAY[0.5, 0.9, 0.95]) WITHIN GROUP (ORDER BY ttr)
+		FROM (
+			SELECT prr.user_id, u.team_name,
+			       prr.reviewed_at IS NULL AND p.status = 'OPEN' AS pending,
+			       EXTRACT(EPOCH FROM prr.reviewed_at - prr.assigned_at)::float8 AS ttr
+			FROM pr_reviewers prr
+			JOIN prs p ON p.pull_request_id = prr.pull_request_id
+			JOIN users u ON u.user_id = prr.user_id
+			WHERE `+where+`
+		) d
+		GROUP BY user_id, team_name
+		ORDER BY team_name, user_id
+	`, a...)
+	if err != nil {
+		return nil, err
+	}
+	res := []ReviewerLatency{}
+	var v ReviewerLatency
+	var ttr []float64
+	_, err = pgx.ForEachRow(rows, []any{
+		&v.UserID, &v.TeamName, &v.Assigned, &v.Pending,
+		&v.TimeToReview.Count, &v.TimeToReview.Mean, &ttr,
+	}, func() error {
+		v.TimeToReview.setPercentiles(ttr)
+		res = append(res, v)
+		v = ReviewerLatency{}
+		return nil
+	})
+	return res, err
+}
+
+// where filters on the user's team (u) and on the window column col.
+func (f LatencyFilter) where(a *args, col string) string {
+	where := []string{"u.team_name IS NOT NULL"}
+	if f.TeamName != "" {
+		where = append(where, "u.team_name="+a.add(f.TeamName))
+	}
+	if f.From != nil {
+		where = append(where, col+">="+a.add(*f.From))
+	}
+	if f.To != nil {
+		where = append(where, col+"<"+a.add(*f.To))
+	}
+	return strings.Join(where, " AND ")
+}
+
+// setPercentiles takes the result of percentile_cont over (0.5, 0.9, 0.95),
+// which is NULL when there was nothing to aggregate.
+func (l *Latency) setPercentiles(p []float64) {
+	l.P50, l.P90, l.P95 = nil, nil, nil
+	if len(p) == 3 {
+		p50, p90, p95 := p[0], p[1], p[2]
+		l.P50, l.P90, l.P95 = &p50, &p90, &p95
+	}
+}
@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("INVALID_CURSOR")
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// Sort keys accepted by the list queries. Every list is additionally ordered by
+// its identifier so that keyset cursors are stable.
+const (
+	SortCreatedAt = "created_at"
+	SortCount     = "count"
+	SortID        = "id"
+)
+
+// Page describes a keyset-paginated, optionally time-bounded list request.
+// From is inclusive, To is exclusive.
+type Page struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Desc   bool
+	From   *time.Time
+	To     *time.Time
+}
+
+func (p Page) limit() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	default:
+		return p.Limit
+	}
+}
+
+func (p Page) order() string {
+	if p.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// cmp is the keyset comparison operator matching the sort direction.
+func (p Page) cmp() string {
+	if p.Desc {
+		return "<"
+	}
+	return ">"
+}
+
+type cursor struct {
+	Key string `json:"k,omitempty"`
+	ID  string `json:"id"`
+}
+
+func encodeCursor(key, id string) string {
+	b, _ := json.Marshal(cursor{Key: key, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func (c cursor) timeKey() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Key)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+func (c cursor) intKey() (int64, error) {
+	n, err := strconv.ParseInt(c.Key, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return n, nil
+}
+
+// args collects positional query arguments while a statement is assembled.
+type args []any
+
+func (a *args) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
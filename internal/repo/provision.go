@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+
+	"reviewer-service/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Offset-paged listings and membership updates for identity provisioning
+// (SCIM), where clients page with startIndex/count and expect a total.
+
+// ListUsersOffset returns users ordered by user_id, optionally only userID,
+// together with the total number of matching users.
+func (r *Repo) ListUsersOffset(ctx context.Context, userID string, offset, limit int) ([]models.User, int, error) {
+	var total int
+	err := r.pool.QueryRow(ctx, `
+		SELECT count(*) FROM users WHERE $1::text = '' OR user_id = $1
+	`, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	users, err := r.listUsers(ctx, r.pool, `$1::text = '' OR user_id = $1 ORDER BY user_id OFFSET $2 LIMIT $3`, userID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// ListTeamNamesOffset is ListUsersOffset for teams.
+func (r *Repo) ListTeamNamesOffset(ctx context.Context, team string, offset, limit int) ([]string, int, error) {
+	var total int
+	err := r.pool.QueryRow(ctx, `
+		SELECT count(*) FROM teams WHERE $1::text = '' OR team_name = $1
+	`, team).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT team_name FROM teams
+		WHERE $1::text = '' OR team_name = $1
+		ORDER BY team_name OFFSET $2 LIMIT $3
+	`, team, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	res := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, 0, err
+		}
+		res = append(res, name)
+	}
+	return res, total, rows.Err()
+}
+
+// SetUsersTeamTx moves ids to team; an empty team leaves them without one.
+func (r *Repo) SetUsersTeamTx(ctx context.Context, tx pgx.Tx, ids []string, team string) error {
+	_, err := tx.Exec(ctx, `UPDATE users SET team_name = NULLIF($2, '') WHERE user_id = ANY($1)`, ids, team)
+	return err
+}
+
+// DeleteTeamTx removes a team; its members are left without a team.
+func (r *Repo) DeleteTeamTx(ctx context.Context, tx pgx.Tx, team string) error {
+	ct, err := tx.Exec(ctx, `DELETE FROM teams WHERE team_name = $1`, team)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package repo
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"reviewer-service/internal/models"
 
@@ -45,7 +47,7 @@ func (r *Repo) ListTeamMembers(ctx context.Context, team string) ([]models.TeamM
 	}
 	defer rows.Close()
 
-	var res []models.TeamMember
+	res := []models.TeamMember{}
 	for rows.Next() {
 		var m models.TeamMember
 		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive); err != nil {
@@ -58,10 +60,11 @@ func (r *Repo) ListTeamMembers(ctx context.Context, team string) ([]models.TeamM
 
 // -------------------- Users --------------------
 
+// UpsertUserTx creates or overwrites a user; an empty team means none.
 func (r *Repo) UpsertUserTx(ctx context.Context, tx pgx.Tx, id, name string, active bool, team string) error {
 	_, err := tx.Exec(ctx, `
 		INSERT INTO users(user_id, username, is_active, team_name)
-		VALUES($1,$2,$3,$4)
+		VALUES($1,$2,$3,NULLIF($4,''))
 		ON CONFLICT(user_id) DO UPDATE SET
 			username=EXCLUDED.username,
 			is_active=EXCLUDED.is_active,
@@ -107,11 +110,11 @@ func (r *Repo) PRExistsTx(ctx context.Context, tx pgx.Tx, prID string) (bool, er
 	return ok, err
 }
 
-func (r *Repo) CreatePRTx(ctx context.Context, tx pgx.Tx, id, name, author string) error {
+func (r *Repo) CreatePRTx(ctx context.Context, tx pgx.Tx, id, name, author string, labels []string) error {
 	_, err := tx.Exec(ctx, `
-		INSERT INTO prs(pull_request_id, pull_request_name, author_id, status)
-		VALUES($1,$2,$3,'OPEN')
-	`, id, name, author)
+		INSERT INTO prs(pull_request_id, pull_request_name, author_id, status, labels)
+		VALUES($1,$2,$3,'OPEN',$4)
+	`, id, name, author, labels)
 	return err
 }
 
@@ -130,7 +133,7 @@ func (r *Repo) InsertReviewersTx(ctx context.Context, tx pgx.Tx, prID string, re
 
 func (r *Repo) ReplaceReviewerTx(ctx context.Context, tx pgx.Tx, prID, oldID, newID string) error {
 	ct, err := tx.Exec(ctx, `
-		UPDATE pr_reviewers SET user_id=$3
+		UPDATE pr_reviewers SET user_id=$3, assigned_at=now(), reviewed_at=NULL
 		WHERE pull_request_id=$1 AND user_id=$2
 	`, prID, oldID, newID)
 	if err != nil {
@@ -142,16 +145,30 @@ func (r *Repo) ReplaceReviewerTx(ctx context.Context, tx pgx.Tx, prID, oldID, ne
 	return nil
 }
 
+// MarkReviewedTx records that userID reviewed prID and returns the time of
+// their first review; later calls keep it. pgx.ErrNoRows means userID is not
+// a reviewer of prID.
+func (r *Repo) MarkReviewedTx(ctx context.Context, tx pgx.Tx, prID, userID string) (time.Time, error) {
+	var at time.Time
+	err := tx.QueryRow(ctx, `
+		UPDATE pr_reviewers SET reviewed_at=COALESCE(reviewed_at, now())
+		WHERE pull_request_id=$1 AND user_id=$2
+		RETURNING reviewed_at
+	`, prID, userID).Scan(&at)
+	return at, err
+}
+
 func (r *Repo) GetPRForUpdateTx(ctx context.Context, tx pgx.Tx, prID string) (models.PullRequest, error) {
 	var pr models.PullRequest
 	err := tx.QueryRow(ctx, `
-		SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
-		FROM prs WHERE pull_request_id=$1 FOR UPDATE
+		SELECT pull_request_id, pull_request_name, author_id, status, labels, created_at, merged_at
+		FROM prs WHERE pull_request_id=$1 FOR NO KEY UPDATE
 	`, prID).Scan(
 		&pr.PullRequestID,
 		&pr.PullRequestName,
 		&pr.AuthorID,
 		&pr.Status,
+		&pr.Labels,
 		&pr.CreatedAt,
 		&pr.MergedAt,
 	)
@@ -182,7 +199,7 @@ func (r *Repo) ListPRReviewerIDsTx(ctx context.Context, q querier, prID string)
 	}
 	defer rows.Close()
 
-	var res []string
+	res := []string{}
 	for rows.Next() {
 		var id string
 		if err := rows.Scan(&id); err != nil {
@@ -200,13 +217,14 @@ func (r *Repo) ListPRReviewerIDs(ctx context.Context, prID string) ([]string, er
 func (r *Repo) GetPR(ctx context.Context, prID string) (models.PullRequest, error) {
 	var pr models.PullRequest
 	err := r.pool.QueryRow(ctx, `
-		SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
+		SELECT pull_request_id, pull_request_name, author_id, status, labels, created_at, merged_at
 		FROM prs WHERE pull_request_id=$1
 	`, prID).Scan(
 		&pr.PullRequestID,
 		&pr.PullRequestName,
 		&pr.AuthorID,
 		&pr.Status,
+		&pr.Labels,
 		&pr.CreatedAt,
 		&pr.MergedAt,
 	)
@@ -222,55 +240,298 @@ func (r *Repo) GetPR(ctx context.Context, prID string) (models.PullRequest, erro
 	return pr, nil
 }
 
-func (r *Repo) ListActiveTeamUserIDsTx(ctx context.Context, tx pgx.Tx, team string, exclude []string) ([]string, error) {
-	ex := map[string]struct{}{}
-	for _, e := range exclude {
-		ex[e] = struct{}{}
+// Candidate is an active team member who may be picked as a reviewer.
+// OpenReviews counts their assignments on open PRs.
+type Candidate struct {
+	UserID         string
+	OpenReviews    int
+	MaxOpenReviews *int
+	ReviewWeight   int
+	Level          *string
+	Skills         []string
+	Timezone       *string
+	WorkStart      *string
+	WorkEnd        *string
+}
+
+// AtCapacity reports whether the candidate has reached max_open_reviews.
+func (c Candidate) AtCapacity() bool {
+	return c.MaxOpenReviews != nil && c.OpenReviews >= *c.MaxOpenReviews
+}
+
+// ListCandidatesTx returns the active members of team with a non-zero review
+// weight, except exclude and those who blocked author or any of labels. The
+// members with a review limit are locked first, so that concurrent
+// assignments see each other's reviews in the counts; the caller must hold
+// the team row already.
+func (r *Repo) ListCandidatesTx(ctx context.Context, tx pgx.Tx, team string, exclude []string, author string, labels []string) ([]Candidate, error) {
+	if exclude == nil {
+		exclude = []string{}
+	}
+	if labels == nil {
+		labels = []string{}
+	}
+	if _, err := tx.Exec(ctx, `
+		SELECT 1 FROM users
+		WHERE team_name=$1 AND is_active=true AND review_weight > 0 AND max_open_reviews IS NOT NULL
+		ORDER BY user_id FOR NO KEY UPDATE
+	`, team); err != nil {
+		return nil, err
 	}
 
 	rows, err := tx.Query(ctx, `
-		SELECT user_id
-		FROM users
-		WHERE team_name=$1 AND is_active=true
-	`, team)
+		SELECT u.user_id, u.max_open_reviews, u.review_weight, u.level, u.skills, u.timezone, u.work_start, u.work_end,
+		       (SELECT COUNT(*) FROM pr_reviewers prr
+		        JOIN prs p ON p.pull_request_id = prr.pull_request_id
+		        WHERE prr.user_id = u.user_id AND p.status = 'OPEN')::int
+		FROM users u
+		WHERE u.team_name=$1 AND u.is_active=true AND u.review_weight > 0 AND NOT (u.user_id = ANY($2))
+		  AND NOT ($3 = ANY(u.blocked_authors)) AND NOT (u.blocked_labels && $4)
+		ORDER BY u.user_id
+	`, team, exclude, author, labels)
 	if err != nil {
 		return nil, err
 	}
+	var res []Candidate
+	var c Candidate
+	_, err = pgx.ForEachRow(rows, []any{&c.UserID, &c.MaxOpenReviews, &c.ReviewWeight, &c.Level, &c.Skills,
+		&c.Timezone, &c.WorkStart, &c.WorkEnd, &c.OpenReviews}, func() error {
+		res = append(res, c)
+		c = Candidate{}
+		return nil
+	})
+	return res, err
+}
+
+// ListReviewerTraitsTx returns the level and skills of the users ids, for
+// matching reviewer rules; the other Candidate fields are left zero.
+func (r *Repo) ListReviewerTraitsTx(ctx context.Context, tx pgx.Tx, ids []string) ([]Candidate, error) {
+	rows, err := tx.Query(ctx, `SELECT user_id, level, skills FROM users WHERE user_id = ANY($1) ORDER BY user_id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var res []Candidate
+	var c Candidate
+	_, err = pgx.ForEachRow(rows, []any{&c.UserID, &c.Level, &c.Skills}, func() error {
+		res = append(res, c)
+		c = Candidate{}
+		return nil
+	})
+	return res, err
+}
+
+// RecentPairingsTx counts, per user, the latest limit PRs of author other
+// than prID that the user was ever assigned to review. Users never assigned
+// to them are left out.
+func (r *Repo) RecentPairingsTx(ctx context.Context, tx pgx.Tx, author, prID string, limit int) (map[string]int, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT ra.assigned_user_id, COUNT(DISTINCT ra.pull_request_id)::int
+		FROM (
+			SELECT pull_request_id FROM prs
+			WHERE author_id=$1 AND pull_request_id<>$2
+			ORDER BY created_at DESC, pull_request_id DESC
+			LIMIT $3
+		) recent
+		JOIN review_assignments ra ON ra.pull_request_id = recent.pull_request_id
+		GROUP BY ra.assigned_user_id
+	`, author, prID, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]int{}
+	var id string
+	var n int
+	_, err = pgx.ForEachRow(rows, []any{&id, &n}, func() error {
+		res[id] = n
+		return nil
+	})
+	return res, err
+}
+
+func (r *Repo) ListPRShortByReviewer(ctx context.Context, reviewer string, status models.PRStatus, p Page) ([]models.PullRequestShort, string, error) {
+	var a args
+	where := []string{"prr.user_id=" + a.add(reviewer)}
+	if status != "" {
+		where = append(where, "p.status="+a.add(status))
+	}
+	if p.From != nil {
+		where = append(where, "p.created_at>="+a.add(*p.From))
+	}
+	if p.To != nil {
+		where = append(where, "p.created_at<"+a.add(*p.To))
+	}
+
+	where, orderBy, err := prKeyset(p, &a, where)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := p.limit()
+	rows, err := r.pool.Query(ctx, `
+		SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.status, p.created_at
+		FROM pr_reviewers prr
+		JOIN prs p ON p.pull_request_id=prr.pull_request_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+orderBy+`
+		LIMIT `+a.add(limit+1), a...)
+	if err != nil {
+		return nil, "", err
+	}
 	defer rows.Close()
 
-	var res []string
+	var res []models.PullRequestShort
+	var created []time.Time
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
+		var pr models.PullRequestShort
+		var createdAt time.Time
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &createdAt); err != nil {
+			return nil, "", err
 		}
-		if _, ok := ex[id]; !ok {
-			res = append(res, id)
+		res = append(res, pr)
+		created = append(created, createdAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(res) <= limit {
+		return res, "", nil
+	}
+	res = res[:limit]
+	return res, encodeCursor(created[limit-1].Format(time.RFC3339Nano), res[limit-1].PullRequestID), nil
+}
+
+type PRFilter struct {
+	AuthorID     string
+	TeamName     string
+	ReviewerID   string
+	Status       models.PRStatus
+	NameContains string
+	MergedFrom   *time.Time
+	MergedTo     *time.Time
+}
+
+// ListPRs returns a page of pull requests matching f; p.From/p.To bound created_at.
+func (r *Repo) ListPRs(ctx context.Context, f PRFilter, p Page) ([]models.PullRequest, string, error) {
+	var a args
+	where := []string{"TRUE"}
+	if f.AuthorID != "" {
+		where = append(where, "p.author_id="+a.add(f.AuthorID))
+	}
+	if f.TeamName != "" {
+		where = append(where, "p.author_id IN (SELECT user_id FROM users WHERE team_name="+a.add(f.TeamName)+")")
+	}
+	if f.ReviewerID != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM pr_reviewers prr WHERE prr.pull_request_id=p.pull_request_id AND prr.user_id="+a.add(f.ReviewerID)+")")
+	}
+	if f.Status != "" {
+		where = append(where, "p.status="+a.add(f.Status))
+	}
+	if f.NameContains != "" {
+		where = append(where, "p.pull_request_name ILIKE '%' || "+a.add(escapeLike(f.NameContains))+" || '%'")
+	}
+	if f.MergedFrom != nil {
+		where = append(where, "p.merged_at>="+a.add(*f.MergedFrom))
+	}
+	if f.MergedTo != nil {
+		where = append(where, "p.merged_at<"+a.add(*f.MergedTo))
+	}
+	if p.From != nil {
+		where = append(where, "p.created_at>="+a.add(*p.From))
+	}
+	if p.To != nil {
+		where = append(where, "p.created_at<"+a.add(*p.To))
+	}
+
+	where, orderBy, err := prKeyset(p, &a, where)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := p.limit()
+	rows, err := r.pool.Query(ctx, `
+		SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.status, p.labels, p.created_at, p.merged_at
+		FROM prs p
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+orderBy+`
+		LIMIT `+a.add(limit+1), a...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var res []models.PullRequest
+	for rows.Next() {
+		var pr models.PullRequest
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.Labels, &pr.CreatedAt, &pr.MergedAt); err != nil {
+			return nil, "", err
 		}
+		res = append(res, pr)
 	}
-	return res, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(res) > limit {
+		res = res[:limit]
+		last := res[limit-1]
+		next = encodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.PullRequestID)
+	}
+
+	if err := r.fillReviewers(ctx, res); err != nil {
+		return nil, "", err
+	}
+	return res, next, nil
 }
 
-func (r *Repo) ListPRShortByReviewer(ctx context.Context, reviewer string) ([]models.PullRequestShort, error) {
+func (r *Repo) ListReviewerIDsByPRs(ctx context.Context, prIDs []string) (map[string][]string, error) {
 	rows, err := r.pool.Query(ctx, `
-		SELECT p.pull_request_id, p.pull_request_name, p.author_id, p.status
-		FROM pr_reviewers prr
-		JOIN prs p ON p.pull_request_id=prr.pull_request_id
-		WHERE prr.user_id=$1
-		ORDER BY p.created_at DESC
-	`, reviewer)
+		SELECT pull_request_id, user_id FROM pr_reviewers
+		WHERE pull_request_id = ANY($1)
+		ORDER BY pull_request_id, position
+	`, prIDs)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var res []models.PullRequestShort
+	res := map[string][]string{}
 	for rows.Next() {
-		var pr models.PullRequestShort
-		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status); err != nil {
+		var prID, uid string
+		if err := rows.Scan(&prID, &uid); err != nil {
 			return nil, err
 		}
-		res = append(res, pr)
+		res[prID] = append(res[prID], uid)
 	}
 	return res, rows.Err()
 }
+
+// prKeyset adds the cursor condition for a query over prs aliased as p and
+// returns the matching ORDER BY clause.
+func prKeyset(p Page, a *args, where []string) ([]string, string, error) {
+	orderBy := "p.created_at " + p.order() + ", p.pull_request_id " + p.order()
+	if p.Sort == SortID {
+		orderBy = "p.pull_request_id " + p.order()
+	}
+	if p.Cursor == "" {
+		return where, orderBy, nil
+	}
+
+	c, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if p.Sort == SortID {
+		return append(where, "p.pull_request_id"+p.cmp()+a.add(c.ID)), orderBy, nil
+	}
+	t, err := c.timeKey()
+	if err != nil {
+		return nil, "", err
+	}
+	return append(where, "(p.created_at, p.pull_request_id)"+p.cmp()+"("+a.add(t)+"::timestamp, "+a.add(c.ID)+")"), orderBy, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StaleReview is an open review that is still waiting, past a team SLA.
+type StaleReview struct {
+	PullRequestID string
+	UserID        string
+	AssignedAt    time.Time
+}
+
+// staleReviews selects the unreviewed assignments on open PRs that are older
+// than the reviewer team's setting col.
+func staleReviews(col string) string {
+	return `
+		SELECT prr.pull_request_id, prr.user_id, prr.assigned_at
+		FROM pr_reviewers prr
+		JOIN prs p ON p.pull_request_id = prr.pull_request_id
+		JOIN users u ON u.user_id = prr.user_id
+		JOIN teams t ON t.team_name = u.team_name
+		WHERE p.status = 'OPEN' AND prr.reviewed_at IS NULL
+		  AND t.` + col + ` IS NOT NULL
+		  AND prr.assigned_at <= now() - make_interval(secs => t.` + col + `)
+	`
+}
+
+// RecordDueReminders stores a reminder for every review past its team's
+// review SLA that has not been reminded about yet, and returns those.
+func (r *Repo) RecordDueReminders(ctx context.Context) ([]StaleReview, error) {
+	rows, err := r.pool.Query(ctx, `
+		INSERT INTO review_reminders(pull_request_id, user_id, assigned_at)
+		`+staleReviews("review_sla_seconds")+`
+		ON CONFLICT DO NOTHING
+		RETURNING pull_request_id, user_id, assigned_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return collectStale(rows)
+}
+
+// ListOverdueReviews returns the reviews past their team's escalation
+// threshold, oldest first.
+func (r *Repo) ListOverdueReviews(ctx context.Context) ([]StaleReview, error) {
+	rows, err := r.pool.Query(ctx, staleReviews("escalate_after_seconds")+`
+		ORDER BY prr.assigned_at, prr.pull_request_id, prr.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return collectStale(rows)
+}
+
+func collectStale(rows pgx.Rows) ([]StaleReview, error) {
+	var res []StaleReview
+	var v StaleReview
+	_, err := pgx.ForEachRow(rows, []any{&v.PullRequestID, &v.UserID, &v.AssignedAt}, func() error {
+		res = append(res, v)
+		return nil
+	})
+	return res, err
+}
+
+// GetReviewerTx returns when userID was assigned to prID and when they
+// reviewed it (nil if not yet).
+func (r *Repo) GetReviewerTx(ctx context.Context, tx pgx.Tx, prID, userID string) (time.Time, *time.Time, error) {
+	var assigned time.Time
+	var reviewed *time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT assigned_at, reviewed_at FROM pr_reviewers
+		WHERE pull_request_id=$1 AND user_id=$2
+	`, prID, userID).Scan(&assigned, &reviewed)
+	return assigned, reviewed, err
+}
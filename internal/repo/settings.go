@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"reviewer-service/internal/models"
+)
+
+const teamSettingsCols = `team_name, review_sla_seconds, escalate_after_seconds, assignment_strategy, rotation_cursor, reviewer_rules, pair_avoidance_prs, prefer_working_hours`
+
+func scanTeamSettings(row pgx.Row) (models.TeamSettings, error) {
+	var s models.TeamSettings
+	err := row.Scan(&s.TeamName, &s.ReviewSLASeconds, &s.EscalateAfterSeconds, &s.AssignmentStrategy, &s.RotationCursor, &s.ReviewerRules, &s.PairAvoidancePRs, &s.PreferWorkingHours)
+	return s, err
+}
+
+func (r *Repo) GetTeamSettings(ctx context.Context, team string) (models.TeamSettings, error) {
+	return scanTeamSettings(r.pool.QueryRow(ctx, `SELECT `+teamSettingsCols+` FROM teams WHERE team_name=$1`, team))
+}
+
+func (r *Repo) GetTeamSettingsForUpdateTx(ctx context.Context, tx pgx.Tx, team string) (models.TeamSettings, error) {
+	return scanTeamSettings(tx.QueryRow(ctx, `SELECT `+teamSettingsCols+` FROM teams WHERE team_name=$1 FOR UPDATE`, team))
+}
+
+func (r *Repo) SetTeamSettingsTx(ctx context.Context, tx pgx.Tx, s models.TeamSettings) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE teams SET review_sla_seconds=$2, escalate_after_seconds=$3, assignment_strategy=$4, reviewer_rules=$5,
+		                 pair_avoidance_prs=$6, prefer_working_hours=$7
+		WHERE team_name=$1
+	`, s.TeamName, s.ReviewSLASeconds, s.EscalateAfterSeconds, s.AssignmentStrategy, s.ReviewerRules, s.PairAvoidancePRs,
+		s.PreferWorkingHours)
+	return err
+}
+
+// GetTeamSelectionForUpdateTx returns the settings of team that drive
+// reviewer selection and locks its row until tx ends, so that concurrent
+// selections for the team take turns.
+func (r *Repo) GetTeamSelectionForUpdateTx(ctx context.Context, tx pgx.Tx, team string) (models.TeamSettings, error) {
+	return scanTeamSettings(tx.QueryRow(ctx, `SELECT `+teamSettingsCols+` FROM teams WHERE team_name=$1 FOR NO KEY UPDATE`, team))
+}
+
+func (r *Repo) SetRotationCursorTx(ctx context.Context, tx pgx.Tx, team, cursor string) error {
+	_, err := tx.Exec(ctx, `UPDATE teams SET rotation_cursor=$2 WHERE team_name=$1`, team, cursor)
+	return err
+}
+
+const userSettingsCols = `user_id, max_open_reviews, review_weight, level, skills, blocked_authors, blocked_labels, timezone, work_start, work_end`
+
+func scanUserSettings(row pgx.Row) (models.UserSettings, error) {
+	var s models.UserSettings
+	err := row.Scan(&s.UserID, &s.MaxOpenReviews, &s.ReviewWeight, &s.Level, &s.Skills, &s.BlockedAuthors, &s.BlockedLabels, &s.Timezone, &s.WorkStart, &s.WorkEnd)
+	return s, err
+}
+
+func (r *Repo) GetUserSettings(ctx context.Context, userID string) (models.UserSettings, error) {
+	return scanUserSettings(r.pool.QueryRow(ctx, `SELECT `+userSettingsCols+` FROM users WHERE user_id=$1`, userID))
+}
+
+func (r *Repo) GetUserSettingsForUpdateTx(ctx context.Context, tx pgx.Tx, userID string) (models.UserSettings, error) {
+	return scanUserSettings(tx.QueryRow(ctx, `SELECT `+userSettingsCols+` FROM users WHERE user_id=$1 FOR UPDATE`, userID))
+}
+
+func (r *Repo) SetUserSettingsTx(ctx context.Context, tx pgx.Tx, s models.UserSettings) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE users SET max_open_reviews=$2, review_weight=$3, level=$4, skills=$5,
+		                 blocked_authors=$6, blocked_labels=$7, timezone=$8, work_start=$9, work_end=$10
+		WHERE user_id=$1
+	`, s.UserID, s.MaxOpenReviews, s.ReviewWeight, s.Level, s.Skills, s.BlockedAuthors, s.BlockedLabels,
+		s.Timezone, s.WorkStart, s.WorkEnd)
+	return err
+}
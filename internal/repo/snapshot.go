@@ -0,0 +1,275 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Snapshot rows mirror the tables one to one; the JSON names are the column
+// names. Idempotency keys, sent reminders and job runs are not part of a
+// snapshot. Columns added in later versions are pointers, slices or raw JSON,
+// so that a row from an older snapshot restores them as NULL or the column
+// default.
+
+type SnapshotTeam struct {
+	TeamName             string  `json:"team_name"`
+	ReviewSLASeconds     *int    `json:"review_sla_seconds"`
+	EscalateAfterSeconds *int    `json:"escalate_after_seconds"`
+	AssignmentStrategy   *string `json:"assignment_strategy"`
+	RotationCursor       *string `json:"rotation_cursor"`
+	// ReviewerRules is kept as the stored JSON.
+	ReviewerRules      json.RawMessage `json:"reviewer_rules"`
+	PairAvoidancePRs   *int            `json:"pair_avoidance_prs"`
+	PreferWorkingHours *bool           `json:"prefer_working_hours"`
+}
+
+type SnapshotUser struct {
+	UserID         string   `json:"user_id"`
+	Username       string   `json:"username"`
+	IsActive       bool     `json:"is_active"`
+	TeamName       *string  `json:"team_name"`
+	MaxOpenReviews *int     `json:"max_open_reviews"`
+	ReviewWeight   *int     `json:"review_weight"`
+	Level          *string  `json:"level"`
+	Skills         []string `json:"skills"`
+	BlockedAuthors []string `json:"blocked_authors"`
+	BlockedLabels  []string `json:"blocked_labels"`
+	Timezone       *string  `json:"timezone"`
+	WorkStart      *string  `json:"work_start"`
+	WorkEnd        *string  `json:"work_end"`
+}
+
+type SnapshotPR struct {
+	PullRequestID   string     `json:"pull_request_id"`
+	PullRequestName string     `json:"pull_request_name"`
+	AuthorID        string     `json:"author_id"`
+	Status          string     `json:"status"`
+	Labels          []string   `json:"labels"`
+	CreatedAt       time.Time  `json:"created_at"`
+	MergedAt        *time.Time `json:"merged_at"`
+}
+
+type SnapshotReviewer struct {
+	PullRequestID string     `json:"pull_request_id"`
+	UserID        string     `json:"user_id"`
+	Position      int16      `json:"position"`
+	AssignedAt    *time.Time `json:"assigned_at"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+}
+
+type SnapshotAssignment struct {
+	ID             int64     `json:"id"`
+	PullRequestID  string    `json:"pull_request_id"`
+	AssignedUserID string    `json:"assigned_user_id"`
+	Action         string    `json:"action"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// -------- export --------
+
+func (r *Repo) ExportTeamsTx(ctx context.Context, tx pgx.Tx, fn func(SnapshotTeam) error) error {
+	var v SnapshotTeam
+	rows, err := tx.Query(ctx, `
+		SELECT team_name, review_sla_seconds, escalate_after_seconds, assignment_strategy, rotation_cursor,
+		       reviewer_rules, pair_avoidance_prs, prefer_working_hours
+		FROM teams ORDER BY team_name
+	`)
+	if err != nil {
+		return err
+	}
+	dest := []any{&v.TeamName, &v.ReviewSLASeconds, &v.EscalateAfterSeconds, &v.AssignmentStrategy, &v.RotationCursor,
+		&v.ReviewerRules, &v.PairAvoidancePRs, &v.PreferWorkingHours}
+	_, err = pgx.ForEachRow(rows, dest, func() error { return fn(v) })
+	return err
+}
+
+func (r *Repo) ExportUsersTx(ctx context.Context, tx pgx.Tx, fn func(SnapshotUser) error) error {
+	var v SnapshotUser
+	rows, err := tx.Query(ctx, `
+		SELECT user_id, username, is_active, team_name, max_open_reviews, review_weight, level, skills,
+		       blocked_authors, blocked_labels, timezone, work_start, work_end
+		FROM users ORDER BY user_id
+	`)
+	if err != nil {
+		return err
+	}
+	dest := []any{&v.UserID, &v.Username, &v.IsActive, &v.TeamName, &v.MaxOpenReviews, &v.ReviewWeight, &v.Level, &v.Skills,
+		&v.BlockedAuthors, &v.BlockedLabels, &v.Timezone, &v.WorkStart, &v.WorkEnd}
+	_, err = pgx.ForEachRow(rows, dest, func() error { return fn(v) })
+	return err
+}
+
+func (r *Repo) ExportPRsTx(ctx context.Context, tx pgx.Tx, fn func(SnapshotPR) error) error {
+	var v SnapshotPR
+	rows, err := tx.Query(ctx, `
+		SELECT pull_request_id, pull_request_name, author_id, status, labels, created_at, merged_at
+		FROM prs ORDER BY pull_request_id
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = pgx.ForEachRow(rows, []any{&v.PullRequestID, &v.PullRequestName, &v.AuthorID, &v.Status, &v.Labels, &v.CreatedAt, &v.MergedAt},
+		func() error { return fn(v) })
+	return err
+}
+
+func (r *Repo) ExportReviewersTx(ctx context.Context, tx pgx.Tx, fn func(SnapshotReviewer) error) error {
+	var v SnapshotReviewer
+	rows, err := tx.Query(ctx, `
+		SELECT pull_request_id, user_id, position, assigned_at, reviewed_at
+		FROM pr_reviewers ORDER BY pull_request_id, position
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = pgx.ForEachRow(rows, []any{&v.PullRequestID, &v.UserID, &v.Position, &v.AssignedAt, &v.ReviewedAt},
+		func() error { return fn(v) })
+	return err
+}
+
+func (r *Repo) ExportAssignmentsTx(ctx context.Context, tx pgx.Tx, fn func(SnapshotAssignment) error) error {
+	var v SnapshotAssignment
+	rows, err := tx.Query(ctx, `
+		SELECT id, pull_request_id, assigned_user_id, action, created_at
+		FROM review_assignments ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = pgx.ForEachRow(rows, []any{&v.ID, &v.PullRequestID, &v.AssignedUserID, &v.Action, &v.CreatedAt},
+		func() error { return fn(v) })
+	return err
+}
+
+// -------- restore --------
+
+// LockSnapshotTablesTx blocks writers (and other restores) until tx ends.
+func (r *Repo) LockSnapshotTablesTx(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `LOCK TABLE teams, users, prs, pr_reviewers, review_assignments IN SHARE ROW EXCLUSIVE MODE`)
+	return err
+}
+
+func (r *Repo) SnapshotTablesEmptyTx(ctx context.Context, tx pgx.Tx) (bool, error) {
+	var empty bool
+	err := tx.QueryRow(ctx, `
+		SELECT NOT EXISTS (SELECT 1 FROM teams)
+		   AND NOT EXISTS (SELECT 1 FROM users)
+		   AND NOT EXISTS (SELECT 1 FROM prs)
+		   AND NOT EXISTS (SELECT 1 FROM review_assignments)
+	`).Scan(&empty)
+	return empty, err
+}
+
+func (r *Repo) RestoreTeamsTx(ctx context.Context, tx pgx.Tx, vs []SnapshotTeam) error {
+	cols := []string{"team_name", "review_sla_seconds", "escalate_after_seconds", "assignment_strategy", "rotation_cursor",
+		"reviewer_rules", "pair_avoidance_prs", "prefer_working_hours"}
+	return copyRows(ctx, tx, "teams", cols, vs, func(v SnapshotTeam) []any {
+		strategy := "RANDOM"
+		if v.AssignmentStrategy != nil {
+			strategy = *v.AssignmentStrategy
+		}
+		rules := v.ReviewerRules
+		if len(rules) == 0 || string(rules) == "null" {
+			rules = json.RawMessage(`[]`)
+		}
+		preferWorkingHours := v.PreferWorkingHours != nil && *v.PreferWorkingHours
+		return []any{v.TeamName, v.ReviewSLASeconds, v.EscalateAfterSeconds, strategy, v.RotationCursor,
+			rules, v.PairAvoidancePRs, preferWorkingHours}
+	})
+}
+
+func (r *Repo) RestoreUsersTx(ctx context.Context, tx pgx.Tx, vs []SnapshotUser) error {
+	cols := []string{"user_id", "username", "is_active", "team_name", "max_open_reviews", "review_weight", "level", "skills",
+		"blocked_authors", "blocked_labels", "timezone", "work_start", "work_end"}
+	return copyRows(ctx, tx, "users", cols, vs, func(v SnapshotUser) []any {
+		weight := 1
+		if v.ReviewWeight != nil {
+			weight = *v.ReviewWeight
+		}
+		return []any{v.UserID, v.Username, v.IsActive, v.TeamName, v.MaxOpenReviews, weight, v.Level, nonNil(v.Skills),
+			nonNil(v.BlockedAuthors), nonNil(v.BlockedLabels), v.Timezone, v.WorkStart, v.WorkEnd}
+	})
+}
+
+func (r *Repo) RestorePRsTx(ctx context.Context, tx pgx.Tx, vs []SnapshotPR) error {
+	cols := []string{"pull_request_id", "pull_request_name", "author_id", "status", "labels", "created_at", "merged_at"}
+	return copyRows(ctx, tx, "prs", cols, vs, func(v SnapshotPR) []any {
+		return []any{v.PullRequestID, v.PullRequestName, v.AuthorID, v.Status, nonNil(v.Labels), v.CreatedAt, v.MergedAt}
+	})
+}
+
+// RestoreReviewersTx restores reviewers; those without assigned_at, from
+// snapshots older than review tracking, get their PR's created_at, like
+// migration 0006 gave them. The PRs must be restored already.
+func (r *Repo) RestoreReviewersTx(ctx context.Context, tx pgx.Tx, vs []SnapshotReviewer) error {
+	var dated, undated []SnapshotReviewer
+	for _, v := range vs {
+		if v.AssignedAt != nil {
+			dated = append(dated, v)
+		} else {
+			undated = append(undated, v)
+		}
+	}
+	cols := []string{"pull_request_id", "user_id", "position", "assigned_at", "reviewed_at"}
+	err := copyRows(ctx, tx, "pr_reviewers", cols, dated, func(v SnapshotReviewer) []any {
+		return []any{v.PullRequestID, v.UserID, v.Position, *v.AssignedAt, v.ReviewedAt}
+	})
+	if err != nil || len(undated) == 0 {
+		return err
+	}
+
+	prIDs := make([]string, len(undated))
+	userIDs := make([]string, len(undated))
+	positions := make([]int16, len(undated))
+	reviewedAt := make([]*time.Time, len(undated))
+	for i, v := range undated {
+		prIDs[i], userIDs[i], positions[i], reviewedAt[i] = v.PullRequestID, v.UserID, v.Position, v.ReviewedAt
+	}
+	// A reviewer of a missing PR fails on the foreign key, whatever the
+	// fallback time.
+	_, err = tx.Exec(ctx, `
+		INSERT INTO pr_reviewers(pull_request_id, user_id, position, assigned_at, reviewed_at)
+		SELECT v.pr_id, v.user_id, v.position, COALESCE(p.created_at, now()), v.reviewed_at
+		FROM unnest($1::text[], $2::text[], $3::smallint[], $4::timestamp[]) AS v(pr_id, user_id, position, reviewed_at)
+		LEFT JOIN prs p ON p.pull_request_id = v.pr_id
+	`, prIDs, userIDs, positions, reviewedAt)
+	return err
+}
+
+func (r *Repo) RestoreAssignmentsTx(ctx context.Context, tx pgx.Tx, vs []SnapshotAssignment) error {
+	cols := []string{"id", "pull_request_id", "assigned_user_id", "action", "created_at"}
+	return copyRows(ctx, tx, "review_assignments", cols, vs, func(v SnapshotAssignment) []any {
+		return []any{v.ID, v.PullRequestID, v.AssignedUserID, v.Action, v.CreatedAt}
+	})
+}
+
+// ResetSequencesTx moves the review_assignments id sequence past the restored
+// ids, so new assignments do not collide with them.
+func (r *Repo) ResetSequencesTx(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `
+		SELECT setval(pg_get_serial_sequence('review_assignments', 'id'), COALESCE(MAX(id), 0) + 1, false)
+		FROM review_assignments
+	`)
+	return err
+}
+
+// nonNil turns a list missing from an older snapshot into an empty one.
+func nonNil(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func copyRows[T any](ctx context.Context, tx pgx.Tx, table string, cols []string, vs []T, values func(T) []any) error {
+	if len(vs) == 0 {
+		return nil
+	}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{table}, cols, pgx.CopyFromSlice(len(vs), func(i int) ([]any, error) {
+		return values(vs[i]), nil
+	}))
+	return err
+}
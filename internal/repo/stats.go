@@ -2,6 +2,9 @@ package repo
 
 import (
 	"context"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -29,48 +32,232 @@ type PRAssignStat struct {
 	Count         int64  `json:"count"`
 }
 
-func (r *Repo) StatsByUsers(ctx context.Context) ([]UserAssignStat, error) {
+func (r *Repo) StatsByUsers(ctx context.Context, p Page) ([]UserAssignStat, string, error) {
+	ids, counts, next, err := r.assignmentCounts(ctx, assignmentsByUser, "assigned_user_id", p)
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]UserAssignStat, len(ids))
+	for i := range ids {
+		res[i] = UserAssignStat{UserID: ids[i], Count: counts[i]}
+	}
+	return res, next, nil
+}
+
+func (r *Repo) StatsByPRs(ctx context.Context, p Page) ([]PRAssignStat, string, error) {
+	ids, counts, next, err := r.assignmentCounts(ctx, assignmentsByUser, "pull_request_id", p)
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]PRAssignStat, len(ids))
+	for i := range ids {
+		res[i] = PRAssignStat{PullRequestID: ids[i], Count: counts[i]}
+	}
+	return res, next, nil
+}
+
+type TeamAssignStat struct {
+	TeamName string `json:"team_name"`
+	Count    int64  `json:"count"`
+}
+
+func (r *Repo) StatsByTeams(ctx context.Context, p Page) ([]TeamAssignStat, string, error) {
+	ids, counts, next, err := r.assignmentCounts(ctx, assignmentsByTeam, "users.team_name", p)
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]TeamAssignStat, len(ids))
+	for i := range ids {
+		res[i] = TeamAssignStat{TeamName: ids[i], Count: counts[i]}
+	}
+	return res, next, nil
+}
+
+// MemberLoad is how many assignments an active team member got.
+type MemberLoad struct {
+	TeamName string
+	UserID   string
+	Count    int64
+}
+
+// ActiveMemberLoads counts the assignments in [from, to) of every active
+// member of team (of every team when team is empty), including members with
+// none, ordered by team and user.
+func (r *Repo) ActiveMemberLoads(ctx context.Context, team string, from, to *time.Time) ([]MemberLoad, error) {
+	var a args
+	on := []string{"ra.assigned_user_id = u.user_id"}
+	if from != nil {
+		on = append(on, "ra.created_at>="+a.add(*from))
+	}
+	if to != nil {
+		on = append(on, "ra.created_at<"+a.add(*to))
+	}
+	where := []string{"u.is_active", "u.team_name IS NOT NULL"}
+	if team != "" {
+		where = append(where, "u.team_name="+a.add(team))
+	}
 	rows, err := r.pool.Query(ctx, `
-		SELECT assigned_user_id, COUNT(*)::bigint
-		FROM review_assignments
-		GROUP BY assigned_user_id
-		ORDER BY COUNT(*) DESC
-	`)
+		SELECT u.team_name, u.user_id, COUNT(ra.id)::bigint
+		FROM users u
+		LEFT JOIN review_assignments ra ON `+strings.Join(on, " AND ")+`
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY u.team_name, u.user_id
+		ORDER BY u.team_name, u.user_id
+	`, a...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	var res []MemberLoad
+	var v MemberLoad
+	_, err = pgx.ForEachRow(rows, []any{&v.TeamName, &v.UserID, &v.Count}, func() error {
+		res = append(res, v)
+		return nil
+	})
+	return res, err
+}
 
-	var res []UserAssignStat
-	for rows.Next() {
-		var s UserAssignStat
-		if err := rows.Scan(&s.UserID, &s.Count); err != nil {
-			return nil, err
-		}
-		res = append(res, s)
+// Sources for assignmentCounts. Team stats attribute an assignment to the
+// reviewer's current team.
+const (
+	assignmentsByUser = `review_assignments`
+	assignmentsByTeam = `review_assignments JOIN users ON users.user_id = review_assignments.assigned_user_id`
+)
+
+// StatsPoint is the number of assignments in the bucket starting at Start.
+type StatsPoint struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// Series is the assignment history of one user or team: Total over the
+// whole window and the non-empty buckets in time order.
+type Series struct {
+	Key    string
+	Total  int64
+	Points []StatsPoint
+}
+
+// SeriesByUsers returns one page of users (ordered like StatsByUsers) with
+// their assignments bucketed by bucket, which is day, week or month.
+func (r *Repo) SeriesByUsers(ctx context.Context, bucket string, p Page) ([]Series, string, error) {
+	return r.assignmentSeries(ctx, assignmentsByUser, "assigned_user_id", bucket, p)
+}
+
+// SeriesByTeams is SeriesByUsers per team.
+func (r *Repo) SeriesByTeams(ctx context.Context, bucket string, p Page) ([]Series, string, error) {
+	return r.assignmentSeries(ctx, assignmentsByTeam, "users.team_name", bucket, p)
+}
+
+func (r *Repo) assignmentSeries(ctx context.Context, from, col, bucket string, p Page) ([]Series, string, error) {
+	ids, counts, next, err := r.assignmentCounts(ctx, from, col, p)
+	if err != nil || len(ids) == 0 {
+		return nil, "", err
+	}
+
+	var a args
+	where := []string{col + "=ANY(" + a.add(ids) + ")"}
+	if p.From != nil {
+		where = append(where, "created_at>="+a.add(*p.From))
+	}
+	if p.To != nil {
+		where = append(where, "created_at<"+a.add(*p.To))
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+col+`, date_trunc(`+a.add(bucket)+`, created_at) AS start, COUNT(*)::bigint
+		FROM `+from+`
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, a...)
+	if err != nil {
+		return nil, "", err
+	}
+	points := map[string][]StatsPoint{}
+	var key string
+	var pt StatsPoint
+	_, err = pgx.ForEachRow(rows, []any{&key, &pt.Start, &pt.Count}, func() error {
+		points[key] = append(points[key], pt)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	res := make([]Series, len(ids))
+	for i, id := range ids {
+		res[i] = Series{Key: id, Total: counts[i], Points: points[id]}
 	}
-	return res, rows.Err()
+	return res, next, nil
 }
 
-func (r *Repo) StatsByPRs(ctx context.Context) ([]PRAssignStat, error) {
+// assignmentCounts groups the assignments in from by col (both trusted SQL)
+// and returns one keyset page of (id, count) pairs.
+func (r *Repo) assignmentCounts(ctx context.Context, from, col string, p Page) ([]string, []int64, string, error) {
+	var a args
+	inner := []string{col + " IS NOT NULL"}
+	if p.From != nil {
+		inner = append(inner, "created_at>="+a.add(*p.From))
+	}
+	if p.To != nil {
+		inner = append(inner, "created_at<"+a.add(*p.To))
+	}
+
+	orderBy := "cnt " + p.order() + ", id " + p.order()
+	if p.Sort == SortID {
+		orderBy = "id " + p.order()
+	}
+
+	outer := "TRUE"
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if p.Sort == SortID {
+			outer = "id" + p.cmp() + a.add(c.ID)
+		} else {
+			n, err := c.intKey()
+			if err != nil {
+				return nil, nil, "", err
+			}
+			outer = "(cnt, id)" + p.cmp() + "(" + a.add(n) + "::bigint, " + a.add(c.ID) + ")"
+		}
+	}
+
+	limit := p.limit()
 	rows, err := r.pool.Query(ctx, `
-		SELECT pull_request_id, COUNT(*)::bigint
-		FROM review_assignments
-		GROUP BY pull_request_id
-		ORDER BY COUNT(*) DESC
-	`)
+		SELECT id, cnt FROM (
+			SELECT `+col+` AS id, COUNT(*)::bigint AS cnt
+			FROM `+from+`
+			WHERE `+strings.Join(inner, " AND ")+`
+			GROUP BY `+col+`
+		) s
+		WHERE `+outer+`
+		ORDER BY `+orderBy+`
+		LIMIT `+a.add(limit+1), a...)
 	if err != nil {
-		return nil, err
+		return nil, nil, "", err
 	}
 	defer rows.Close()
 
-	var res []PRAssignStat
+	var ids []string
+	var counts []int64
 	for rows.Next() {
-		var s PRAssignStat
-		if err := rows.Scan(&s.PullRequestID, &s.Count); err != nil {
-			return nil, err
+		var id string
+		var n int64
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, nil, "", err
 		}
-		res = append(res, s)
+		ids = append(ids, id)
+		counts = append(counts, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, "", err
+	}
+
+	if len(ids) <= limit {
+		return ids, counts, "", nil
 	}
-	return res, rows.Err()
+	ids, counts = ids[:limit], counts[:limit]
+	return ids, counts, encodeCursor(strconv.FormatInt(counts[limit-1], 10), ids[limit-1]), nil
 }
@@ -0,0 +1,22 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"reviewer-service/internal/service"
+)
+
+// IdempotencyPurge is the job that deletes expired idempotency keys every
+// interval.
+func IdempotencyPurge(svc *service.Service, interval time.Duration) Job {
+	return Job{
+		Name:        "idempotency_purge",
+		Description: "delete idempotency keys past their IDEMPOTENCY_TTL",
+		Interval:    interval,
+		Run: func(ctx context.Context) (any, error) {
+			n, err := svc.PurgeIdempotencyKeys(ctx)
+			return map[string]int64{"deleted": n}, err
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"reviewer-service/internal/service"
+)
+
+// StaleReviews is the job that runs svc.ProcessStaleReviews every interval
+// and logs every reminder and escalation as a JSON line.
+func StaleReviews(svc *service.Service, interval time.Duration) Job {
+	return Job{
+		Name:        "stale_reviews",
+		Description: "remind reviewers past their team's review SLA, reassign reviews past the escalation threshold",
+		Interval:    interval,
+		Run: func(ctx context.Context) (any, error) {
+			res, err := svc.ProcessStaleReviews(ctx, logEvent)
+			if res.Stuck > 0 {
+				log.Printf("stale reviews: %d overdue reviews have no escalation candidate", res.Stuck)
+			}
+			return res, err
+		},
+	}
+}
+
+func logEvent(e service.ReviewEvent) {
+	b, _ := json.Marshal(e)
+	log.Printf("review event: %s", b)
+}
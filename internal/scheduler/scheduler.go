@@ -0,0 +1,315 @@
+// Package scheduler runs the server's periodic background work.
+//
+// Every replica runs a Scheduler, but only the leader, the one holding a
+// Postgres advisory lock, starts scheduled runs. While a job runs it holds a
+// lock of its own, so the same job never runs twice at once, even when it is
+// triggered by hand on another replica. Every run is recorded in job_runs.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+	"reviewer-service/internal/service"
+)
+
+const (
+	leaderLock    = "reviewer-service/scheduler/leader"
+	jobLockPrefix = "reviewer-service/scheduler/job/"
+
+	// tick is how often followers try to become the leader and the leader
+	// looks for due jobs.
+	tick = time.Second
+	// runsKept is how many runs of each job job_runs keeps.
+	runsKept = 100
+)
+
+// Job is a named piece of background work.
+type Job struct {
+	Name        string
+	Description string
+	// Interval is the time between the starts of scheduled runs; with 0 the
+	// job only runs when triggered.
+	Interval time.Duration
+	// Run does the work. Its result, if not nil, is stored with the run as JSON.
+	Run func(ctx context.Context) (any, error)
+}
+
+// JobStatus describes a registered job. NextRunAt is nil for jobs without an
+// interval and for jobs that never ran, which the leader starts right away.
+type JobStatus struct {
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	IntervalSeconds int            `json:"interval_seconds"`
+	LastRun         *models.JobRun `json:"last_run"`
+	NextRunAt       *time.Time     `json:"next_run_at"`
+}
+
+type Scheduler struct {
+	r        *repo.Repo
+	instance string
+	jobs     []Job
+	byName   map[string]Job
+	leader   atomic.Bool
+
+	// ctx is the parent of all runs; Run cancels it on the way out.
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// New returns a Scheduler without jobs. Runs are attributed to this process
+// as host-pid.
+func New(r *repo.Repo) *Scheduler {
+	host, _ := os.Hostname()
+	ctx, stop := context.WithCancel(context.Background())
+	return &Scheduler{
+		r:        r,
+		instance: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		byName:   map[string]Job{},
+		ctx:      ctx,
+		stop:     stop,
+		running:  map[string]bool{},
+	}
+}
+
+// Register adds a job. It must be called before Run, with the same jobs on
+// every replica.
+func (s *Scheduler) Register(j Job) {
+	if j.Name == "" || j.Run == nil {
+		panic("scheduler: job needs a name and a Run func")
+	}
+	if _, ok := s.byName[j.Name]; ok {
+		panic("scheduler: job " + j.Name + " registered twice")
+	}
+	// Schedules have a resolution of one second.
+	if j.Interval > 0 {
+		j.Interval = max(time.Second, j.Interval.Truncate(time.Second))
+	}
+	s.jobs = append(s.jobs, j)
+	s.byName[j.Name] = j
+}
+
+func (s *Scheduler) Instance() string { return s.instance }
+
+// IsLeader reports whether this replica currently starts the scheduled runs.
+func (s *Scheduler) IsLeader() bool { return s.leader.Load() }
+
+// Run takes part in leader election and, while leading, starts due jobs. It
+// returns when ctx is done, after the runs started by this replica (which see
+// their context canceled) have finished.
+func (s *Scheduler) Run(ctx context.Context) {
+	var lock *repo.Lock
+	defer func() {
+		s.mu.Lock()
+		s.stop()
+		s.mu.Unlock()
+		s.wg.Wait()
+		if lock != nil {
+			lock.Release()
+			s.leader.Store(false)
+		}
+	}()
+
+	t := time.NewTicker(tick)
+	defer t.Stop()
+	for {
+		lock = s.elect(ctx, lock)
+		if lock != nil {
+			s.startDue(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// elect keeps or takes the leader lock and returns it, or nil while another
+// replica leads.
+func (s *Scheduler) elect(ctx context.Context, lock *repo.Lock) *repo.Lock {
+	if lock != nil {
+		err := lock.Check(ctx)
+		if err == nil || ctx.Err() != nil {
+			return lock
+		}
+		log.Printf("scheduler: lost leadership: %v", err)
+		lock.Release()
+		s.leader.Store(false)
+	}
+	lock, err := s.r.TryLock(ctx, leaderLock)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("scheduler: leader election: %v", err)
+		}
+		return nil
+	}
+	if lock != nil {
+		log.Printf("scheduler: %s is the leader", s.instance)
+		s.leader.Store(true)
+	}
+	return lock
+}
+
+func (s *Scheduler) startDue(ctx context.Context) {
+	var names []string
+	var secs []int
+	for _, j := range s.jobs {
+		if j.Interval > 0 {
+			names = append(names, j.Name)
+			secs = append(secs, int(j.Interval/time.Second))
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	due, err := s.r.DueJobs(ctx, names, secs)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("scheduler: due jobs: %v", err)
+		}
+		return
+	}
+	for _, name := range due {
+		if _, err := s.start(ctx, name, models.JobTriggerSchedule); err != nil &&
+			!errors.Is(err, service.ErrJobRunning) && ctx.Err() == nil {
+			log.Printf("scheduler: start %s: %v", name, err)
+		}
+	}
+}
+
+// Trigger starts a run of the named job in the background on this replica
+// and returns it. It fails with ErrNotFound for an unknown job and with
+// ErrJobRunning while the job runs anywhere.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (models.JobRun, error) {
+	return s.start(ctx, name, models.JobTriggerManual)
+}
+
+func (s *Scheduler) start(ctx context.Context, name, trigger string) (models.JobRun, error) {
+	j, ok := s.byName[name]
+	if !ok {
+		return models.JobRun{}, service.ErrNotFound
+	}
+
+	s.mu.Lock()
+	switch {
+	case s.ctx.Err() != nil:
+		s.mu.Unlock()
+		return models.JobRun{}, s.ctx.Err()
+	case s.running[name]:
+		s.mu.Unlock()
+		return models.JobRun{}, service.ErrJobRunning
+	}
+	s.running[name] = true
+	s.wg.Add(1)
+	s.mu.Unlock()
+	done := func() {
+		s.mu.Lock()
+		delete(s.running, name)
+		s.mu.Unlock()
+		s.wg.Done()
+	}
+
+	lock, err := s.r.TryLock(ctx, jobLockPrefix+name)
+	if err == nil && lock == nil {
+		err = service.ErrJobRunning
+	}
+	if err != nil {
+		done()
+		return models.JobRun{}, err
+	}
+	run, err := s.r.StartJobRun(ctx, name, trigger, s.instance)
+	if err != nil {
+		lock.Release()
+		done()
+		return models.JobRun{}, err
+	}
+
+	go func() {
+		defer done()
+		defer lock.Release()
+		s.execute(j, run)
+	}()
+	return run, nil
+}
+
+func (s *Scheduler) execute(j Job, run models.JobRun) {
+	res, err := runJob(s.ctx, j)
+
+	status := models.JobSucceeded
+	var msg *string
+	if err != nil {
+		log.Printf("scheduler: job %s (run %d) failed: %v", j.Name, run.ID, err)
+		status = models.JobFailed
+		e := err.Error()
+		msg = &e
+	}
+	var out []byte
+	if res != nil {
+		if out, err = json.Marshal(res); err != nil {
+			log.Printf("scheduler: job %s (run %d): encode result: %v", j.Name, run.ID, err)
+			out = nil
+		}
+	}
+
+	// The run's context may be canceled by now; record the outcome anyway.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.r.FinishJobRun(ctx, run.ID, status, out, msg); err != nil {
+		log.Printf("scheduler: job %s (run %d): record outcome: %v", j.Name, run.ID, err)
+	}
+	if err := s.r.PruneJobRuns(ctx, j.Name, runsKept); err != nil {
+		log.Printf("scheduler: job %s: prune runs: %v", j.Name, err)
+	}
+}
+
+func runJob(ctx context.Context, j Job) (res any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return j.Run(ctx)
+}
+
+// Jobs lists the registered jobs with their latest runs.
+func (s *Scheduler) Jobs(ctx context.Context) ([]JobStatus, error) {
+	last, err := s.r.LastJobRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		st := JobStatus{Name: j.Name, Description: j.Description, IntervalSeconds: int(j.Interval / time.Second)}
+		if run, ok := last[j.Name]; ok {
+			st.LastRun = &run
+			if j.Interval > 0 {
+				next := run.StartedAt.Add(j.Interval)
+				st.NextRunAt = &next
+			}
+		}
+		res = append(res, st)
+	}
+	return res, nil
+}
+
+// Runs returns up to limit latest runs of the named job, or of all jobs when
+// name is empty.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]models.JobRun, error) {
+	if _, ok := s.byName[name]; name != "" && !ok {
+		return nil, service.ErrNotFound
+	}
+	return s.r.ListJobRuns(ctx, name, limit)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+)
+
+var (
+	ErrTeamExists  = errors.New("TEAM_EXISTS")
+	ErrUserExists  = errors.New("USER_EXISTS")
+	ErrPRExists    = errors.New("PR_EXISTS")
+	ErrPRMerged    = errors.New("PR_MERGED")
+	ErrNotAssigned = errors.New("NOT_ASSIGNED")
+	ErrNoCandidate = errors.New("NO_CANDIDATE")
+	ErrNotFound    = errors.New("NOT_FOUND")
+
+	ErrIdempotencyConflict   = errors.New("IDEMPOTENCY_CONFLICT")
+	ErrIdempotencyInProgress = errors.New("IDEMPOTENCY_IN_PROGRESS")
+
+	ErrValidation = errors.New("VALIDATION_ERROR")
+	ErrConflict   = errors.New("CONFLICT")
+	ErrNotEmpty   = errors.New("NOT_EMPTY")
+	ErrJobRunning = errors.New("JOB_RUNNING")
+
+	ErrRulesUnsatisfiable = errors.New("RULES_UNSATISFIABLE")
+
+	// ErrAtCapacity is ErrNoCandidate when the only candidates left are at
+	// their max_open_reviews.
+	ErrAtCapacity = fmt.Errorf("%w: candidates at capacity", ErrNoCandidate)
+)
+
+// notFound maps a missing row (or a nil error for a failed precondition such
+// as a user without a team) to ErrNotFound and passes other errors through.
+func notFound(err error) error {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// FieldError describes a single invalid request field.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError reports one or more invalid request fields. It matches
+// ErrValidation with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Reason)
+	}
+	return "VALIDATION_ERROR: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// Validation accumulates the field errors of a request.
+type Validation []FieldError
+
+func (v *Validation) Add(field, reason string) {
+	*v = append(*v, FieldError{Field: field, Reason: reason})
+}
+
+func (v *Validation) Required(field, value string) {
+	if value == "" {
+		v.Add(field, "required")
+	}
+}
+
+// Merge adds the fields of err when it is a *ValidationError and returns any
+// other error as is.
+func (v *Validation) Merge(err error) error {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		*v = append(*v, ve.Fields...)
+		return nil
+	}
+	return err
+}
+
+// Err returns a *ValidationError with the accumulated fields, or nil.
+func (v Validation) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v}
+}
+
+// Invalid builds a ValidationError for a single field.
+func Invalid(field, reason string) error {
+	return &ValidationError{Fields: []FieldError{{Field: field, Reason: reason}}}
+}
+
+// RulesError reports a team reviewer rule that no eligible member meets. It
+// matches ErrRulesUnsatisfiable with errors.Is.
+type RulesError struct {
+	Rule models.ReviewerRule
+}
+
+func (e *RulesError) Error() string {
+	return "RULES_UNSATISFIABLE: no eligible reviewer for " + e.Rule.String()
+}
+
+func (e *RulesError) Is(target error) bool { return target == ErrRulesUnsatisfiable }
+
+// APIError is the wire representation of an error returned by the API.
+type APIError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+	Status  int          `json:"-"`
+}
+
+func ToHTTPError(err error) APIError {
+	var ve *ValidationError
+	var re *RulesError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &ve):
+		return APIError{Code: "VALIDATION_ERROR", Message: "request validation failed", Details: ve.Fields, Status: 400}
+	case errors.Is(err, repo.ErrInvalidCursor):
+		return APIError{
+			Code:    "VALIDATION_ERROR",
+			Message: "request validation failed",
+			Details: []FieldError{{Field: "cursor", Reason: "malformed or does not match the sort"}},
+			Status:  400,
+		}
+	case errors.Is(err, ErrTeamExists):
+		return APIError{Code: "TEAM_EXISTS", Message: "team_name already exists", Status: 400}
+	case errors.Is(err, ErrUserExists):
+		return APIError{Code: "USER_EXISTS", Message: "user_id already exists", Status: 409}
+	case errors.Is(err, ErrPRExists):
+		return APIError{Code: "PR_EXISTS", Message: "PR id already exists", Status: 409}
+	case errors.Is(err, ErrPRMerged):
+		return APIError{Code: "PR_MERGED", Message: "cannot reassign on merged PR", Status: 409}
+	case errors.Is(err, ErrNotAssigned):
+		return APIError{Code: "NOT_ASSIGNED", Message: "reviewer is not assigned to this PR", Status: 409}
+	case errors.As(err, &re):
+		return APIError{
+			Code:    "RULES_UNSATISFIABLE",
+			Message: "no eligible reviewer meets the team rule " + re.Rule.String(),
+			Status:  409,
+		}
+	case errors.Is(err, ErrAtCapacity):
+		return APIError{Code: "NO_CANDIDATE", Message: "all replacement candidates are at their max_open_reviews", Status: 409}
+	case errors.Is(err, ErrNoCandidate):
+		return APIError{Code: "NO_CANDIDATE", Message: "no active replacement candidate in team", Status: 409}
+	case errors.Is(err, ErrNotFound):
+		return APIError{Code: "NOT_FOUND", Message: "resource not found", Status: 404}
+	case errors.Is(err, ErrIdempotencyConflict):
+		return APIError{Code: "IDEMPOTENCY_CONFLICT", Message: "idempotency key reused with a different request body", Status: 422}
+	case errors.Is(err, ErrIdempotencyInProgress):
+		return APIError{Code: "IDEMPOTENCY_IN_PROGRESS", Message: "request with this idempotency key is still in progress", Status: 409}
+	case errors.Is(err, ErrNotEmpty):
+		return APIError{Code: "NOT_EMPTY", Message: "database already contains data", Status: 409}
+	case errors.Is(err, ErrJobRunning):
+		return APIError{Code: "JOB_RUNNING", Message: "job is already running", Status: 409}
+	case errors.Is(err, ErrConflict),
+		errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return APIError{Code: "CONFLICT", Message: "resource was modified concurrently", Status: 409}
+	default:
+		return APIError{Code: "INTERNAL", Message: "internal error", Status: 500}
+	}
+}
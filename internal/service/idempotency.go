@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type StoredResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// idempotencyBeginAttempts bounds how often IdempotencyBegin reserves a key
+// that vanishes before it can be read.
+const idempotencyBeginAttempts = 3
+
+// IdempotencyBegin reserves key for the given route. A nil response with a nil
+// error means the caller owns the key and must finish it with IdempotencyComplete
+// or IdempotencyRelease; a non-nil response is the stored result to replay.
+func (s *Service) IdempotencyBegin(ctx context.Context, key, method, path, hash string, ttl time.Duration) (*StoredResponse, error) {
+	for range idempotencyBeginAttempts {
+		ok, err := s.r.ReserveIdempotencyKey(ctx, key, method, path, hash, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, nil
+		}
+
+		rec, err := s.r.GetIdempotencyRecord(ctx, key, method, path)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Released by its owner or purged after the reservation failed.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.RequestHash != hash {
+			return nil, ErrIdempotencyConflict
+		}
+		if rec.StatusCode == nil {
+			return nil, ErrIdempotencyInProgress
+		}
+		return &StoredResponse{StatusCode: *rec.StatusCode, Body: rec.Body}, nil
+	}
+	// The key keeps changing hands; the client may retry later.
+	return nil, ErrIdempotencyInProgress
+}
+
+func (s *Service) IdempotencyComplete(ctx context.Context, key, method, path string, resp StoredResponse) error {
+	return s.r.CompleteIdempotencyKey(ctx, key, method, path, resp.StatusCode, resp.Body)
+}
+
+func (s *Service) IdempotencyRelease(ctx context.Context, key, method, path string) error {
+	return s.r.ReleaseIdempotencyKey(ctx, key, method, path)
+}
+
+// idempotencyPurgeBatch bounds the rows one purge statement deletes, so the
+// purge does not hold locks on a large part of the table at once.
+const idempotencyPurgeBatch = 5000
+
+// PurgeIdempotencyKeys deletes the expired idempotency keys and returns how
+// many it deleted.
+func (s *Service) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		n, err := s.r.DeleteExpiredIdempotencyKeys(ctx, idempotencyPurgeBatch)
+		total += n
+		if err != nil || n < idempotencyPurgeBatch {
+			return total, err
+		}
+	}
+}
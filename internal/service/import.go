@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+
+	"reviewer-service/internal/models"
+)
+
+// ImportTeam is the desired state of one team in a bulk import.
+type ImportTeam struct {
+	TeamName string
+	Members  []models.TeamMember
+}
+
+const (
+	ChangeCreateTeam     = "CREATE_TEAM"
+	ChangeCreateUser     = "CREATE_USER"
+	ChangeUpdateUser     = "UPDATE_USER"
+	ChangeMoveUser       = "MOVE_USER"
+	ChangeDeactivateUser = "DEACTIVATE_USER"
+)
+
+// ImportChange is a single step of an import plan. TeamName is the team the
+// user ends up in; FromTeam is set for moves and Fields lists what an update
+// changes.
+type ImportChange struct {
+	Action   string   `json:"action"`
+	TeamName string   `json:"team_name"`
+	UserID   string   `json:"user_id,omitempty"`
+	FromTeam string   `json:"from_team,omitempty"`
+	Fields   []string `json:"fields,omitempty"`
+}
+
+type ImportSummary struct {
+	TeamsCreated     int `json:"teams_created"`
+	UsersCreated     int `json:"users_created"`
+	UsersUpdated     int `json:"users_updated"`
+	UsersMoved       int `json:"users_moved"`
+	UsersDeactivated int `json:"users_deactivated"`
+}
+
+// Plan is what an import or sync changes (or would change, before apply).
+type Plan struct {
+	Changes      []ImportChange    `json:"changes"`
+	Summary      ImportSummary     `json:"summary"`
+	SafeReassign SafeReassignStats `json:"safe_reassign"`
+}
+
+type ImportResult struct {
+	DryRun bool `json:"dry_run"`
+	Plan
+}
+
+// Import creates the listed teams and creates, updates or moves the listed
+// users in a single transaction. Users missing from the import are left
+// alone; users switched to inactive get their open reviews safely reassigned.
+// With dryRun the whole import runs and is rolled back, so the result shows
+// exactly what would happen.
+func (s *Service) Import(ctx context.Context, teams []ImportTeam, dryRun bool) (ImportResult, error) {
+	if err := ValidateImport(teams); err != nil {
+		return ImportResult{}, err
+	}
+
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	plan, err := s.importTx(ctx, tx, teams, nil)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	res := ImportResult{DryRun: dryRun, Plan: plan}
+	if dryRun {
+		return res, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return ImportResult{}, err
+	}
+	return res, nil
+}
+
+const (
+	SyncModePlan  = "plan"
+	SyncModeApply = "apply"
+)
+
+type SyncResult struct {
+	Mode string `json:"mode"`
+	Plan
+}
+
+// Sync reconciles the database with a desired state: missing teams are
+// created, listed users are created, updated or moved, and active members of
+// the listed teams (of all teams with allTeams) that the desired state does
+// not mention are deactivated with safe reassign. Mode plan runs everything
+// and rolls back, apply commits.
+func (s *Service) Sync(ctx context.Context, teams []ImportTeam, mode string, allTeams bool) (SyncResult, error) {
+	if mode != SyncModePlan && mode != SyncModeApply {
+		return SyncResult{}, Invalid("mode", "must be plan or apply")
+	}
+	if err := ValidateImport(teams); err != nil {
+		return SyncResult{}, err
+	}
+
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return SyncResult{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	prune := make([]string, 0, len(teams))
+	for _, t := range teams {
+		prune = append(prune, t.TeamName)
+	}
+	if allTeams {
+		if prune, err = s.r.ListTeamNamesTx(ctx, tx); err != nil {
+			return SyncResult{}, err
+		}
+	}
+
+	plan, err := s.importTx(ctx, tx, teams, prune)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	res := SyncResult{Mode: mode, Plan: plan}
+	if mode == SyncModePlan {
+		return res, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return SyncResult{}, err
+	}
+	return res, nil
+}
+
+// importTx applies teams. Active members of the prune teams that are not
+// listed anywhere in teams are deactivated as well.
+func (s *Service) importTx(ctx context.Context, tx pgx.Tx, teams []ImportTeam, prune []string) (Plan, error) {
+	names := make([]string, 0, len(teams))
+	var ids []string
+	desired := map[string]bool{}
+	for _, t := range teams {
+		names = append(names, t.TeamName)
+		for _, m := range t.Members {
+			ids = append(ids, m.UserID)
+			desired[m.UserID] = true
+		}
+	}
+
+	existingTeams, err := s.r.ExistingTeamsTx(ctx, tx, names)
+	if err != nil {
+		return Plan{}, err
+	}
+	list, err := s.lockUsersWithTeamsTx(ctx, tx, ids, prune, names)
+	if err != nil {
+		return Plan{}, err
+	}
+	current := make(map[string]models.User, len(list))
+	for _, u := range list {
+		current[u.UserID] = u
+	}
+
+	res := Plan{Changes: []ImportChange{}}
+	var deactivated []string
+	for _, t := range teams {
+		if !existingTeams[t.TeamName] {
+			if err := s.r.CreateTeamTx(ctx, tx, t.TeamName); err != nil {
+				return Plan{}, err
+			}
+			res.Changes = append(res.Changes, ImportChange{Action: ChangeCreateTeam, TeamName: t.TeamName})
+			res.Summary.TeamsCreated++
+		}
+
+		for _, m := range t.Members {
+			changes := diffMember(t.TeamName, m, current)
+			if len(changes) == 0 {
+				continue
+			}
+			if err := s.r.UpsertUserTx(ctx, tx, m.UserID, m.Username, m.IsActive, t.TeamName); err != nil {
+				return Plan{}, err
+			}
+			for _, c := range changes {
+				switch c.Action {
+				case ChangeCreateUser:
+					res.Summary.UsersCreated++
+				case ChangeUpdateUser:
+					res.Summary.UsersUpdated++
+				case ChangeMoveUser:
+					res.Summary.UsersMoved++
+				case ChangeDeactivateUser:
+					res.Summary.UsersDeactivated++
+					deactivated = append(deactivated, m.UserID)
+				}
+			}
+			res.Changes = append(res.Changes, changes...)
+		}
+	}
+
+	// list is ordered by user_id, which keeps the plan stable.
+	pruneTeams := map[string]bool{}
+	for _, t := range prune {
+		pruneTeams[t] = true
+	}
+	removed := map[string][]string{}
+	for _, u := range list {
+		if pruneTeams[u.TeamName] && u.IsActive && !desired[u.UserID] {
+			removed[u.TeamName] = append(removed[u.TeamName], u.UserID)
+			res.Changes = append(res.Changes, ImportChange{Action: ChangeDeactivateUser, TeamName: u.TeamName, UserID: u.UserID})
+			res.Summary.UsersDeactivated++
+		}
+	}
+	for _, team := range prune {
+		if len(removed[team]) == 0 {
+			continue
+		}
+		ids, err := s.r.DeactivateUsersTx(ctx, tx, team, removed[team])
+		if err != nil {
+			return Plan{}, err
+		}
+		deactivated = append(deactivated, ids...)
+	}
+
+	res.SafeReassign, err = s.safeReassignTx(ctx, tx, deactivated)
+	if err != nil {
+		return Plan{}, err
+	}
+	return res, nil
+}
+
+// diffMember lists the changes that bring the user to member m of team.
+func diffMember(team string, m models.TeamMember, current map[string]models.User) []ImportChange {
+	cur, ok := current[m.UserID]
+	if !ok {
+		return []ImportChange{{Action: ChangeCreateUser, TeamName: team, UserID: m.UserID}}
+	}
+
+	var changes []ImportChange
+	if cur.TeamName != team {
+		changes = append(changes, ImportChange{Action: ChangeMoveUser, TeamName: team, UserID: m.UserID, FromTeam: cur.TeamName})
+	}
+	var fields []string
+	if cur.Username != m.Username {
+		fields = append(fields, "username")
+	}
+	if !cur.IsActive && m.IsActive {
+		fields = append(fields, "is_active")
+	}
+	if len(fields) > 0 {
+		changes = append(changes, ImportChange{Action: ChangeUpdateUser, TeamName: team, UserID: m.UserID, Fields: fields})
+	}
+	if cur.IsActive && !m.IsActive {
+		changes = append(changes, ImportChange{Action: ChangeDeactivateUser, TeamName: team, UserID: m.UserID})
+	}
+	return changes
+}
+
+// ValidateImport checks that team names and user ids are present and that
+// neither a team nor a user is listed twice.
+func ValidateImport(teams []ImportTeam) error {
+	var v Validation
+	seenTeams := map[string]bool{}
+	seenUsers := map[string]bool{}
+	for i, t := range teams {
+		prefix := "teams[" + strconv.Itoa(i) + "]."
+		v.Required(prefix+"team_name", t.TeamName)
+		if t.TeamName != "" && seenTeams[t.TeamName] {
+			v.Add(prefix+"team_name", "duplicate team_name")
+		}
+		seenTeams[t.TeamName] = true
+
+		for j, m := range t.Members {
+			mp := prefix + "members[" + strconv.Itoa(j) + "]."
+			v.Required(mp+"user_id", m.UserID)
+			if m.UserID != "" && seenUsers[m.UserID] {
+				v.Add(mp+"user_id", "user listed more than once")
+			}
+			seenUsers[m.UserID] = true
+			v.Required(mp+"username", m.Username)
+		}
+	}
+	return v.Err()
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"reviewer-service/internal/models"
+)
+
+// User and team management for identity provisioning (SCIM). Unlike
+// TeamAdd, users exist on their own and join or leave teams later.
+
+// UserPatch lists the user fields to change; nil fields are kept.
+type UserPatch struct {
+	Username *string
+	IsActive *bool
+}
+
+func (s *Service) UserGet(ctx context.Context, userID string) (models.User, error) {
+	u, err := s.r.GetUser(ctx, userID)
+	if err != nil {
+		return models.User{}, notFound(err)
+	}
+	return u, nil
+}
+
+// UserList returns a page of users (only userID when it is set) and the total.
+func (s *Service) UserList(ctx context.Context, userID string, offset, limit int) ([]models.User, int, error) {
+	return s.r.ListUsersOffset(ctx, userID, offset, limit)
+}
+
+// UserCreate adds a user without a team.
+func (s *Service) UserCreate(ctx context.Context, userID, username string, active bool) (models.User, error) {
+	var v Validation
+	v.Required("user_id", userID)
+	v.Required("username", username)
+	if err := v.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.User{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	existing, err := s.r.LockUsersTx(ctx, tx, []string{userID}, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(existing) > 0 {
+		return models.User{}, ErrUserExists
+	}
+	if err := s.r.UpsertUserTx(ctx, tx, userID, username, active, ""); err != nil {
+		return models.User{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.User{}, err
+	}
+	return s.r.GetUser(ctx, userID)
+}
+
+// UserUpdate applies p. Deactivating a user reassigns their open reviews the
+// same way TeamDeactivate does.
+func (s *Service) UserUpdate(ctx context.Context, userID string, p UserPatch) (models.User, error) {
+	if p.Username != nil && *p.Username == "" {
+		return models.User{}, Invalid("username", "must not be empty")
+	}
+
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.User{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	u, err := s.lockUserWithTeamTx(ctx, tx, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	wasActive := u.IsActive
+	if p.Username != nil {
+		u.Username = *p.Username
+	}
+	if p.IsActive != nil {
+		u.IsActive = *p.IsActive
+	}
+	if err := s.r.UpsertUserTx(ctx, tx, u.UserID, u.Username, u.IsActive, u.TeamName); err != nil {
+		return models.User{}, err
+	}
+	if wasActive && !u.IsActive {
+		if _, err := s.safeReassignTx(ctx, tx, []string{u.UserID}); err != nil {
+			return models.User{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// lockUserWithTeamTx locks the user and, before them, their team row.
+func (s *Service) lockUserWithTeamTx(ctx context.Context, tx pgx.Tx, userID string) (models.User, error) {
+	list, err := s.lockUsersWithTeamsTx(ctx, tx, []string{userID}, nil, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(list) == 0 {
+		return models.User{}, ErrNotFound
+	}
+	return list[0], nil
+}
+
+// lockUsersWithTeamsTx is LockUsersTx that first locks the rows of teams,
+// others and the current teams of ids, the order reviewer selection takes
+// them in.
+func (s *Service) lockUsersWithTeamsTx(ctx context.Context, tx pgx.Tx, ids, teams, others []string) ([]models.User, error) {
+	cur, err := s.r.ListUsersByIDsTx(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	locked := slices.Concat(teams, others)
+	for _, u := range cur {
+		locked = append(locked, u.TeamName)
+	}
+	if err := s.r.LockTeamsTx(ctx, tx, locked); err != nil {
+		return nil, err
+	}
+	list, err := s.r.LockUsersTx(ctx, tx, ids, teams)
+	if err != nil {
+		return nil, err
+	}
+	// A user who moved before their row was locked cannot move any more, so
+	// their new team is locked out of order only in this rare case.
+	var moved []string
+	for _, u := range list {
+		if !slices.Contains(locked, u.TeamName) {
+			moved = append(moved, u.TeamName)
+		}
+	}
+	if len(moved) > 0 {
+		if err := s.r.LockTeamsTx(ctx, tx, moved); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// TeamList returns a page of teams with their members (only team when it is
+// set) and the total.
+func (s *Service) TeamList(ctx context.Context, team string, offset, limit int) ([]models.Team, int, error) {
+	names, total, err := s.r.ListTeamNamesOffset(ctx, team, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	users, err := s.r.ListUsersByTeams(ctx, names)
+	if err != nil {
+		return nil, 0, err
+	}
+	members := map[string][]models.TeamMember{}
+	for _, u := range users {
+		members[u.TeamName] = append(members[u.TeamName], models.TeamMember{UserID: u.UserID, Username: u.Username, IsActive: u.IsActive})
+	}
+
+	teams := make([]models.Team, 0, len(names))
+	for _, name := range names {
+		t := models.Team{TeamName: name, Members: members[name]}
+		if t.Members == nil {
+			t.Members = []models.TeamMember{}
+		}
+		teams = append(teams, t)
+	}
+	return teams, total, nil
+}
+
+// TeamCreate creates a team and moves the existing users memberIDs into it.
+func (s *Service) TeamCreate(ctx context.Context, team string, memberIDs []string) (models.Team, error) {
+	if team == "" {
+		return models.Team{}, Invalid("team_name", "required")
+	}
+
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Team{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	exists, err := s.r.TeamExistsTx(ctx, tx, team)
+	if err != nil {
+		return models.Team{}, err
+	}
+	if exists {
+		return models.Team{}, ErrTeamExists
+	}
+	if err := s.r.CreateTeamTx(ctx, tx, team); err != nil {
+		return models.Team{}, err
+	}
+	if err := s.setMembersTx(ctx, tx, team, memberIDs, nil, false); err != nil {
+		return models.Team{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Team{}, err
+	}
+	return s.TeamGet(ctx, team)
+}
+
+// TeamUpdateMembers moves the users in add into team (out of their previous
+// team) and takes the members in remove out of it. With replace, every
+// current member not in add is removed. Removed users keep their reviews and
+// are left without a team.
+func (s *Service) TeamUpdateMembers(ctx context.Context, team string, add, remove []string, replace bool) (models.Team, error) {
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Team{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	exists, err := s.r.TeamExistsTx(ctx, tx, team)
+	if err != nil {
+		return models.Team{}, err
+	}
+	if !exists {
+		return models.Team{}, ErrNotFound
+	}
+	if err := s.setMembersTx(ctx, tx, team, add, remove, replace); err != nil {
+		return models.Team{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.Team{}, err
+	}
+	return s.TeamGet(ctx, team)
+}
+
+func (s *Service) setMembersTx(ctx context.Context, tx pgx.Tx, team string, add, remove []string, replace bool) error {
+	list, err := s.lockUsersWithTeamsTx(ctx, tx, add, []string{team}, nil)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]models.User, len(list))
+	for _, u := range list {
+		known[u.UserID] = u
+	}
+
+	keep := map[string]bool{}
+	var moveIn []string
+	for _, id := range add {
+		u, ok := known[id]
+		if !ok {
+			return Invalid("members", "unknown user_id "+id)
+		}
+		keep[id] = true
+		if u.TeamName != team {
+			moveIn = append(moveIn, id)
+		}
+	}
+
+	drop := map[string]bool{}
+	for _, id := range remove {
+		drop[id] = true
+	}
+	var moveOut []string
+	for _, u := range list {
+		if u.TeamName != team || keep[u.UserID] {
+			continue
+		}
+		if replace || drop[u.UserID] {
+			moveOut = append(moveOut, u.UserID)
+		}
+	}
+
+	if len(moveIn) > 0 {
+		if err := s.r.SetUsersTeamTx(ctx, tx, moveIn, team); err != nil {
+			return err
+		}
+	}
+	if len(moveOut) > 0 {
+		if err := s.r.SetUsersTeamTx(ctx, tx, moveOut, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TeamDelete removes a team; its members stay, without a team.
+func (s *Service) TeamDelete(ctx context.Context, team string) error {
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.r.DeleteTeamTx(ctx, tx, team); err != nil {
+		return notFound(err)
+	}
+	return tx.Commit(ctx)
+}
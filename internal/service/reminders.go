@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+)
+
+const (
+	EventReminder  = "REMINDER"
+	EventEscalated = "ESCALATED"
+)
+
+// ReviewEvent is emitted for a review that waits longer than its team allows:
+// a reminder to UserID, or an escalation that replaced UserID by ReplacedBy.
+type ReviewEvent struct {
+	Type          string    `json:"type"`
+	PullRequestID string    `json:"pull_request_id"`
+	UserID        string    `json:"user_id"`
+	ReplacedBy    string    `json:"replaced_by,omitempty"`
+	AssignedAt    time.Time `json:"assigned_at"`
+}
+
+type StaleReviewResult struct {
+	Reminded  int `json:"reminded"`
+	Escalated int `json:"escalated"`
+	// Stuck counts overdue reviews with nobody to escalate to; they are
+	// retried on the next pass.
+	Stuck int `json:"stuck"`
+}
+
+// ProcessStaleReviews runs one pass over the open reviews: reviews past the
+// team's review_sla_seconds get one reminder each (per assignment), reviews
+// past escalate_after_seconds are reassigned like PRReassign does and logged
+// as ESCALATE_REASSIGN. Both are reported through emit.
+func (s *Service) ProcessStaleReviews(ctx context.Context, emit func(ReviewEvent)) (StaleReviewResult, error) {
+	var res StaleReviewResult
+
+	due, err := s.r.RecordDueReminders(ctx)
+	if err != nil {
+		return res, err
+	}
+	for _, d := range due {
+		emit(ReviewEvent{Type: EventReminder, PullRequestID: d.PullRequestID, UserID: d.UserID, AssignedAt: d.AssignedAt})
+		res.Reminded++
+	}
+
+	overdue, err := s.r.ListOverdueReviews(ctx)
+	if err != nil {
+		return res, err
+	}
+	for _, o := range overdue {
+		newID, err := s.escalate(ctx, o)
+		switch {
+		case errors.Is(err, ErrNoCandidate), errors.Is(err, ErrRulesUnsatisfiable), errors.Is(err, ErrNotFound):
+			res.Stuck++
+		case err != nil:
+			return res, err
+		case newID != "":
+			emit(ReviewEvent{Type: EventEscalated, PullRequestID: o.PullRequestID, UserID: o.UserID, ReplacedBy: newID, AssignedAt: o.AssignedAt})
+			res.Escalated++
+		}
+	}
+	return res, nil
+}
+
+// escalate reassigns the overdue review o unless it changed since it was
+// listed (reviewed, reassigned or merged), in which case it returns "".
+func (s *Service) escalate(ctx context.Context, o repo.StaleReview) (string, error) {
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pr, err := s.r.GetPRForUpdateTx(ctx, tx, o.PullRequestID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if pr.Status != models.PROpen {
+		return "", nil
+	}
+	assigned, reviewed, err := s.r.GetReviewerTx(ctx, tx, o.PullRequestID, o.UserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if reviewed != nil || !assigned.Equal(o.AssignedAt) {
+		return "", nil
+	}
+
+	newID, err := s.reassignTx(ctx, tx, pr, o.UserID, "ESCALATE_REASSIGN")
+	if err != nil {
+		return "", err
+	}
+	return newID, tx.Commit(ctx)
+}
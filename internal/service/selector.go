@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	crand "crypto/rand"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"reviewer-service/internal/models"
+	"reviewer-service/internal/repo"
+)
+
+const (
+	// reviewersPerPR is how many reviewers PRCreate assigns.
+	reviewersPerPR = 2
+	// MaxReviewWeight bounds a user's review_weight.
+	MaxReviewWeight = 100
+	// MaxPairAvoidancePRs bounds a team's pair_avoidance_prs.
+	MaxPairAvoidancePRs = 100
+)
+
+// CapacityShortfall reports that fewer reviewers than wanted were assigned
+// because other eligible members were at their max_open_reviews.
+type CapacityShortfall struct {
+	Wanted     int      `json:"wanted"`
+	Assigned   int      `json:"assigned"`
+	AtCapacity []string `json:"at_capacity"`
+}
+
+// selection is the outcome of selectReviewersTx.
+type selection struct {
+	picked []string
+	// atCapacity are the members that were skipped for being at their
+	// max_open_reviews.
+	atCapacity []string
+}
+
+// shortfall explains a selection of fewer than n reviewers, if capacity is
+// the reason.
+func (sel selection) shortfall(n int) *CapacityShortfall {
+	if len(sel.picked) >= n || len(sel.atCapacity) == 0 {
+		return nil
+	}
+	return &CapacityShortfall{Wanted: n, Assigned: len(sel.picked), AtCapacity: sel.atCapacity}
+}
+
+// selectRequest describes the reviewers to pick for one PR.
+type selectRequest struct {
+	team string
+	// pr and author identify the PR; the author's other recent PRs tell which
+	// pairings to avoid.
+	pr     string
+	author string
+	// exclude are never picked: the author and the PR's reviewers.
+	exclude []string
+	// keep are the reviewers who stay on the PR; they count towards the
+	// team's reviewer rules.
+	keep   []string
+	labels []string
+	n      int
+}
+
+// selectReviewersTx picks up to req.n reviewers among the active members of
+// the team other than req.exclude, skipping members at their
+// max_open_reviews and members who blocked the author or one of the labels.
+// Members are picked by the team's strategy: at random with a chance
+// proportional to their review_weight, or in turn after the team's rotation
+// cursor, which then moves to the last one picked.
+//
+// The team's reviewer rules that apply to the labels and are not met by
+// req.keep are served first; a *RulesError reports a rule that no eligible
+// member meets or that does not fit into req.n.
+//
+// Every pick prefers, with prefer_working_hours set, the members within their
+// working hours, then, with pair_avoidance_prs set, the members assigned to
+// the fewest of the author's latest PRs, and only then applies the strategy.
+func (s *Service) selectReviewersTx(ctx context.Context, tx pgx.Tx, req selectRequest) (selection, error) {
+	// The team row is locked before the members. Deactivation, imports,
+	// user settings and SCIM user and group updates also lock team rows
+	// before user rows (see lockUsersWithTeamsTx); other writes to users
+	// are single statements that wait for no further locks.
+	ts, err := s.r.GetTeamSelectionForUpdateTx(ctx, tx, req.team)
+	if err != nil {
+		return selection{}, err
+	}
+	cands, err := s.r.ListCandidatesTx(ctx, tx, req.team, req.exclude, req.author, req.labels)
+	if err != nil {
+		return selection{}, err
+	}
+	var sel selection
+	var free []repo.Candidate
+	for _, c := range cands {
+		if c.AtCapacity() {
+			sel.atCapacity = append(sel.atCapacity, c.UserID)
+			continue
+		}
+		free = append(free, c)
+	}
+
+	rules := applicableRules(ts.ReviewerRules, req.labels)
+	if len(rules) > 0 && len(req.keep) > 0 {
+		kept, err := s.r.ListReviewerTraitsTx(ctx, tx, req.keep)
+		if err != nil {
+			return selection{}, err
+		}
+		rules = unmetRules(rules, kept)
+	}
+
+	var pairings map[string]int
+	if ts.PairAvoidancePRs != nil && req.author != "" {
+		if pairings, err = s.r.RecentPairingsTx(ctx, tx, req.author, req.pr, *ts.PairAvoidancePRs); err != nil {
+			return selection{}, err
+		}
+	}
+
+	now := time.Now()
+	preference := func(c repo.Candidate) [2]int {
+		var k [2]int
+		if ts.PreferWorkingHours && !workingAt(c, now) {
+			k[0] = 1
+		}
+		k[1] = pairings[c.UserID]
+		return k
+	}
+
+	roundRobin := ts.AssignmentStrategy == models.StrategyRoundRobin
+	cursor := ts.RotationCursor
+	pick := func(pool []repo.Candidate, n int) []string {
+		var ids []string
+		for _, tier := range byPreference(pool, preference) {
+			var got []string
+			if roundRobin {
+				got = pickNInTurn(tier, cursor, n-len(ids))
+			} else {
+				got = pickNWeighted(tier, n-len(ids))
+			}
+			if len(got) > 0 {
+				cursor = &got[len(got)-1]
+			}
+			if ids = append(ids, got...); len(ids) >= n {
+				break
+			}
+		}
+		return ids
+	}
+
+	for len(rules) > 0 && len(sel.picked) < req.n {
+		pool := bestMatches(free, rules)
+		if len(pool) == 0 {
+			break
+		}
+		id := pick(pool, 1)[0]
+		i := slices.IndexFunc(free, func(c repo.Candidate) bool { return c.UserID == id })
+		rules = unmetRules(rules, free[i:i+1])
+		free = slices.Delete(free, i, i+1)
+		sel.picked = append(sel.picked, id)
+	}
+	if len(rules) > 0 {
+		return selection{}, &RulesError{Rule: rules[0]}
+	}
+	sel.picked = append(sel.picked, pick(free, req.n-len(sel.picked))...)
+
+	if roundRobin && len(sel.picked) > 0 {
+		if err := s.r.SetRotationCursorTx(ctx, tx, req.team, *cursor); err != nil {
+			return selection{}, err
+		}
+	}
+	return sel, nil
+}
+
+func applicableRules(rules []models.ReviewerRule, labels []string) []models.ReviewerRule {
+	var res []models.ReviewerRule
+	for _, r := range rules {
+		if r.IfLabel == "" || slices.Contains(labels, r.IfLabel) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func ruleMatches(r models.ReviewerRule, c repo.Candidate) bool {
+	if r.Level != "" {
+		return c.Level != nil && *c.Level == r.Level
+	}
+	return slices.Contains(c.Skills, r.Skill)
+}
+
+// unmetRules returns the rules that none of reviewers meets.
+func unmetRules(rules []models.ReviewerRule, reviewers []repo.Candidate) []models.ReviewerRule {
+	var res []models.ReviewerRule
+	for _, r := range rules {
+		if !slices.ContainsFunc(reviewers, func(c repo.Candidate) bool { return ruleMatches(r, c) }) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// bestMatches returns the candidates meeting the most of rules, so that two
+// reviewers can cover more than two rules between them; it is empty when no
+// candidate meets any.
+func bestMatches(cands []repo.Candidate, rules []models.ReviewerRule) []repo.Candidate {
+	var res []repo.Candidate
+	best := 0
+	for _, c := range cands {
+		n := 0
+		for _, r := range rules {
+			if ruleMatches(r, c) {
+				n++
+			}
+		}
+		switch {
+		case n == 0 || n < best:
+		case n > best:
+			best, res = n, []repo.Candidate{c}
+		default:
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// byPreference groups cands by key, lowest first, comparing the key's
+// elements in order; with equal keys it is a single group.
+func byPreference(cands []repo.Candidate, key func(repo.Candidate) [2]int) [][]repo.Candidate {
+	keys := make(map[string][2]int, len(cands))
+	for _, c := range cands {
+		keys[c.UserID] = key(c)
+	}
+	cands = slices.Clone(cands)
+	slices.SortStableFunc(cands, func(a, b repo.Candidate) int {
+		ka, kb := keys[a.UserID], keys[b.UserID]
+		return slices.Compare(ka[:], kb[:])
+	})
+	var res [][]repo.Candidate
+	for i := 0; i < len(cands); {
+		j := i + 1
+		for j < len(cands) && keys[cands[j].UserID] == keys[cands[i].UserID] {
+			j++
+		}
+		res = append(res, cands[i:j])
+		i = j
+	}
+	return res
+}
+
+// workingAt reports whether t falls within c's working hours. Members without
+// working hours are always available.
+func workingAt(c repo.Candidate, t time.Time) bool {
+	if c.WorkStart == nil || c.WorkEnd == nil {
+		return true
+	}
+	loc := time.UTC
+	if c.Timezone != nil {
+		l, err := time.LoadLocation(*c.Timezone)
+		if err != nil {
+			return true
+		}
+		loc = l
+	}
+	start, _ := parseClock(*c.WorkStart)
+	end, _ := parseClock(*c.WorkEnd)
+	local := t.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return start <= m && m < end
+	}
+	return m >= start || m < end
+}
+
+// pickNInTurn takes up to n candidates in user id order, starting with the
+// first one after cursor and wrapping around. Members who left, or are
+// skipped this time, do not hold up the rotation.
+func pickNInTurn(cands []repo.Candidate, cursor *string, n int) []string {
+	if n <= 0 || len(cands) == 0 {
+		return nil
+	}
+	cands = slices.Clone(cands)
+	slices.SortFunc(cands, func(a, b repo.Candidate) int { return strings.Compare(a.UserID, b.UserID) })
+	start := 0
+	if cursor != nil {
+		for start < len(cands) && cands[start].UserID <= *cursor {
+			start++
+		}
+		if start == len(cands) {
+			start = 0
+		}
+	}
+	out := make([]string, 0, min(n, len(cands)))
+	for i := 0; i < n && i < len(cands); i++ {
+		out = append(out, cands[(start+i)%len(cands)].UserID)
+	}
+	return out
+}
+
+// pickNWeighted draws up to n distinct candidates without replacement, each
+// draw choosing among the remaining ones with a chance proportional to their
+// weight. With equal weights it is a uniform pick.
+func pickNWeighted(cands []repo.Candidate, n int) []string {
+	if n <= 0 || len(cands) == 0 {
+		return nil
+	}
+	rest := append([]repo.Candidate{}, cands...)
+	total := 0
+	for _, c := range rest {
+		total += c.ReviewWeight
+	}
+	var out []string
+	for len(out) < n && total > 0 {
+		r := cryptoRandInt(total)
+		i := 0
+		for ; r >= rest[i].ReviewWeight; i++ {
+			r -= rest[i].ReviewWeight
+		}
+		out = append(out, rest[i].UserID)
+		total -= rest[i].ReviewWeight
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+	return out
+}
+
+func cryptoRandInt(upper int) int {
+	if upper <= 1 {
+		return 0
+	}
+	nBig, _ := crand.Int(crand.Reader, big.NewInt(int64(upper)))
+	return int(nBig.Int64())
+}
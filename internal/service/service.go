@@ -2,9 +2,10 @@ package service
 
 import (
 	"context"
-	crand "crypto/rand"
 	"errors"
-	"math/big"
+	"slices"
+	"strconv"
+	"time"
 
 	"reviewer-service/internal/models"
 	"reviewer-service/internal/repo"
@@ -12,15 +13,6 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-var (
-	ErrTeamExists  = errors.New("TEAM_EXISTS")
-	ErrPRExists    = errors.New("PR_EXISTS")
-	ErrPRMerged    = errors.New("PR_MERGED")
-	ErrNotAssigned = errors.New("NOT_ASSIGNED")
-	ErrNoCandidate = errors.New("NO_CANDIDATE")
-	ErrNotFound    = errors.New("NOT_FOUND")
-)
-
 type Service struct {
 	r *repo.Repo
 }
@@ -30,6 +22,10 @@ func New(r *repo.Repo) *Service { return &Service{r: r} }
 // -------- Teams --------
 
 func (s *Service) TeamAdd(ctx context.Context, teamName string, members []models.TeamMember) (models.Team, error) {
+	if err := ValidateMembers(members); err != nil {
+		return models.Team{}, err
+	}
+
 	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return models.Team{}, err
@@ -49,9 +45,6 @@ func (s *Service) TeamAdd(ctx context.Context, teamName string, members []models
 	}
 
 	for _, m := range members {
-		if m.UserID == "" || m.Username == "" {
-			return models.Team{}, ErrNotFound
-		}
 		if err := s.r.UpsertUserTx(ctx, tx, m.UserID, m.Username, m.IsActive, teamName); err != nil {
 			return models.Team{}, err
 		}
@@ -67,7 +60,7 @@ func (s *Service) TeamAdd(ctx context.Context, teamName string, members []models
 func (s *Service) TeamGet(ctx context.Context, teamName string) (models.Team, error) {
 	t, err := s.r.GetTeam(ctx, teamName)
 	if err != nil {
-		return models.Team{}, ErrNotFound
+		return models.Team{}, notFound(err)
 	}
 	mm, err := s.r.ListTeamMembers(ctx, teamName)
 	if err != nil {
@@ -77,76 +70,116 @@ func (s *Service) TeamGet(ctx context.Context, teamName string) (models.Team, er
 	return t, nil
 }
 
-func (s *Service) TeamDeactivate(ctx context.Context, team string, userIDs []string) (map[string]any, error) {
+// SafeReassignStats counts the reviews of deactivated users that moved to a
+// teammate or were dropped; RemovedAtCapacity are the drops with teammates
+// left who were all at their max_open_reviews.
+type SafeReassignStats struct {
+	Reassigned        int `json:"reassigned"`
+	Removed           int `json:"removed"`
+	RemovedAtCapacity int `json:"removed_at_capacity"`
+}
+
+type DeactivateResult struct {
+	TeamName     string            `json:"team_name"`
+	Deactivated  []string          `json:"deactivated"`
+	SafeReassign SafeReassignStats `json:"safe_reassign"`
+}
+
+func (s *Service) TeamDeactivate(ctx context.Context, team string, userIDs []string) (DeactivateResult, error) {
 	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return nil, err
+		return DeactivateResult{}, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	if _, err := s.r.GetTeam(ctx, team); err != nil {
-		return nil, ErrNotFound
+		return DeactivateResult{}, notFound(err)
+	}
+	if err := s.r.LockTeamsTx(ctx, tx, []string{team}); err != nil {
+		return DeactivateResult{}, err
 	}
 
 	deactivated, err := s.r.DeactivateUsersTx(ctx, tx, team, userIDs)
 	if err != nil {
-		return nil, err
+		return DeactivateResult{}, err
 	}
 
-	affected, err := s.r.FindAffectedOpenPRsTx(ctx, tx, deactivated)
+	stats, err := s.safeReassignTx(ctx, tx, deactivated)
 	if err != nil {
-		return nil, err
+		return DeactivateResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return DeactivateResult{}, err
+	}
+
+	return DeactivateResult{
+		TeamName:     team,
+		Deactivated:  deactivated,
+		SafeReassign: stats,
+	}, nil
+}
+
+// safeReassignTx replaces the deactivated users on every open PR they review
+// with an active teammate below their review limit, or drops them from the PR
+// when nobody is left or nobody left meets the team's reviewer rules.
+func (s *Service) safeReassignTx(ctx context.Context, tx pgx.Tx, deactivated []string) (SafeReassignStats, error) {
+	var stats SafeReassignStats
+	if len(deactivated) == 0 {
+		return stats, nil
 	}
 
-	reassigned := 0
-	removed := 0
+	affected, err := s.r.FindAffectedOpenPRsTx(ctx, tx, deactivated)
+	if err != nil {
+		return stats, err
+	}
 
 	for _, a := range affected {
 		oldUser, err := s.r.GetUserTx(ctx, tx, a.OldUID)
 		if err != nil || oldUser.TeamName == "" {
 			_ = s.r.DeleteReviewerTx(ctx, tx, a.PRID, a.OldUID)
-			removed++
+			stats.Removed++
 			continue
 		}
 
 		current, _ := s.r.ListPRReviewerIDsTx(ctx, tx, a.PRID)
 		exclude := append([]string{a.Author, a.OldUID}, current...)
+		var keep []string
+		for _, id := range current {
+			if !slices.Contains(deactivated, id) {
+				keep = append(keep, id)
+			}
+		}
 
-		cands, err := s.r.ListActiveTeamUserIDsTx(ctx, tx, oldUser.TeamName, exclude)
-		if err != nil {
-			return nil, err
+		sel, err := s.selectReviewersTx(ctx, tx, selectRequest{
+			team: oldUser.TeamName, pr: a.PRID, author: a.Author,
+			exclude: exclude, keep: keep, labels: a.Labels, n: 1,
+		})
+		if err != nil && !errors.Is(err, ErrRulesUnsatisfiable) {
+			return stats, err
 		}
 
-		if len(cands) == 0 {
+		if len(sel.picked) == 0 {
 			if err := s.r.DeleteReviewerTx(ctx, tx, a.PRID, a.OldUID); err != nil {
-				return nil, err
+				return stats, err
+			}
+			stats.Removed++
+			if len(sel.atCapacity) > 0 {
+				stats.RemovedAtCapacity++
 			}
-			removed++
 			continue
 		}
 
-		newID := pickNRandom(cands, 1)[0]
+		newID := sel.picked[0]
 		if err := s.r.ReplaceReviewerTx(ctx, tx, a.PRID, a.OldUID, newID); err != nil {
-			return nil, err
+			return stats, err
 		}
 		if err := s.r.LogAssignmentsTx(ctx, tx, a.PRID, []string{newID}, "SAFE_REASSIGN"); err != nil {
-			return nil, err
+			return stats, err
 		}
-		reassigned++
+		stats.Reassigned++
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return nil, err
-	}
-
-	return map[string]any{
-		"team_name":   team,
-		"deactivated": deactivated,
-		"safe_reassign": map[string]int{
-			"reassigned": reassigned,
-			"removed":    removed,
-		},
-	}, nil
+	return stats, nil
 }
 
 // -------- Users --------
@@ -154,58 +187,71 @@ func (s *Service) TeamDeactivate(ctx context.Context, team string, userIDs []str
 func (s *Service) UserSetIsActive(ctx context.Context, userID string, active bool) (models.User, error) {
 	u, err := s.r.SetIsActive(ctx, userID, active)
 	if err != nil {
-		return models.User{}, ErrNotFound
+		return models.User{}, notFound(err)
 	}
 	return u, nil
 }
 
-func (s *Service) UserGetReview(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
+func (s *Service) UserGetReview(ctx context.Context, userID string, status models.PRStatus, p repo.Page) ([]models.PullRequestShort, string, error) {
 	_, err := s.r.GetUser(ctx, userID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, "", notFound(err)
 	}
-	return s.r.ListPRShortByReviewer(ctx, userID)
+	return s.r.ListPRShortByReviewer(ctx, userID, status, p)
 }
 
 // -------- PRs --------
 
-func (s *Service) PRCreate(ctx context.Context, prID, prName, authorID string) (models.PullRequest, error) {
+// PRCreate creates the PR with labels and assigns up to two active teammates
+// of the author, picked by the team's assignment strategy and reviewer rules.
+// The shortfall is non-nil when fewer were assigned because teammates were at
+// their max_open_reviews.
+func (s *Service) PRCreate(ctx context.Context, prID, prName, authorID string, labels []string) (models.PullRequest, *CapacityShortfall, error) {
+	labels, err := normalizeTags("labels", labels)
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+
 	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return models.PullRequest{}, err
+		return models.PullRequest{}, nil, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	author, err := s.r.GetUserTx(ctx, tx, authorID)
 	if err != nil || author.TeamName == "" {
-		return models.PullRequest{}, ErrNotFound
+		return models.PullRequest{}, nil, notFound(err)
 	}
 
 	if ok, _ := s.r.PRExistsTx(ctx, tx, prID); ok {
-		return models.PullRequest{}, ErrPRExists
+		return models.PullRequest{}, nil, ErrPRExists
 	}
 
-	cands, err := s.r.ListActiveTeamUserIDsTx(ctx, tx, author.TeamName, []string{author.UserID})
+	sel, err := s.selectReviewersTx(ctx, tx, selectRequest{
+		team: author.TeamName, pr: prID, author: author.UserID,
+		exclude: []string{author.UserID}, labels: labels, n: reviewersPerPR,
+	})
 	if err != nil {
-		return models.PullRequest{}, err
+		return models.PullRequest{}, nil, err
 	}
-	revs := pickNRandom(cands, 2)
+	revs := sel.picked
 
-	if err := s.r.CreatePRTx(ctx, tx, prID, prName, authorID); err != nil {
-		return models.PullRequest{}, err
+	if err := s.r.CreatePRTx(ctx, tx, prID, prName, authorID, labels); err != nil {
+		return models.PullRequest{}, nil, err
 	}
 	if err := s.r.InsertReviewersTx(ctx, tx, prID, revs); err != nil {
-		return models.PullRequest{}, err
+		return models.PullRequest{}, nil, err
 	}
 	if err := s.r.LogAssignmentsTx(ctx, tx, prID, revs, "AUTO_ASSIGN"); err != nil {
-		return models.PullRequest{}, err
+		return models.PullRequest{}, nil, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return models.PullRequest{}, err
+		return models.PullRequest{}, nil, err
 	}
 
-	return s.r.GetPR(ctx, prID)
+	pr, err := s.r.GetPR(ctx, prID)
+	return pr, sel.shortfall(reviewersPerPR), err
 }
 
 func (s *Service) PRMerge(ctx context.Context, prID string) (models.PullRequest, error) {
@@ -217,7 +263,7 @@ func (s *Service) PRMerge(ctx context.Context, prID string) (models.PullRequest,
 
 	pr, err := s.r.GetPRForUpdateTx(ctx, tx, prID)
 	if err != nil {
-		return models.PullRequest{}, ErrNotFound
+		return models.PullRequest{}, notFound(err)
 	}
 
 	if pr.Status == models.PRMerged {
@@ -244,17 +290,35 @@ func (s *Service) PRReassign(ctx context.Context, prID, oldUserID string) (model
 
 	pr, err := s.r.GetPRForUpdateTx(ctx, tx, prID)
 	if err != nil {
-		return models.PullRequest{}, "", ErrNotFound
+		return models.PullRequest{}, "", notFound(err)
 	}
 	if pr.Status == models.PRMerged {
 		return models.PullRequest{}, "", ErrPRMerged
 	}
 
-	reviewers, err := s.r.ListPRReviewerIDsTx(ctx, tx, prID)
+	newID, err := s.reassignTx(ctx, tx, pr, oldUserID, "REASSIGN")
 	if err != nil {
 		return models.PullRequest{}, "", err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return models.PullRequest{}, "", err
+	}
+
+	updated, err := s.r.GetPR(ctx, prID)
+	return updated, newID, err
+}
+
+// reassignTx replaces oldUserID on the locked open PR pr with an active
+// member of oldUserID's team below their review limit, meeting the team's
+// reviewer rules together with the other reviewer, and logs the change as
+// action. It returns the new reviewer.
+func (s *Service) reassignTx(ctx context.Context, tx pgx.Tx, pr models.PullRequest, oldUserID, action string) (string, error) {
+	reviewers, err := s.r.ListPRReviewerIDsTx(ctx, tx, pr.PullRequestID)
+	if err != nil {
+		return "", err
+	}
+
 	found := false
 	var others []string
 	for _, id := range reviewers {
@@ -265,90 +329,102 @@ func (s *Service) PRReassign(ctx context.Context, prID, oldUserID string) (model
 		}
 	}
 	if !found {
-		return models.PullRequest{}, "", ErrNotAssigned
+		return "", ErrNotAssigned
 	}
 
 	oldUser, err := s.r.GetUserTx(ctx, tx, oldUserID)
 	if err != nil || oldUser.TeamName == "" {
-		return models.PullRequest{}, "", ErrNotFound
+		return "", notFound(err)
 	}
 
 	exclude := append([]string{pr.AuthorID, oldUserID}, others...)
-	cands, err := s.r.ListActiveTeamUserIDsTx(ctx, tx, oldUser.TeamName, exclude)
+	sel, err := s.selectReviewersTx(ctx, tx, selectRequest{
+		team: oldUser.TeamName, pr: pr.PullRequestID, author: pr.AuthorID,
+		exclude: exclude, keep: others, labels: pr.Labels, n: 1,
+	})
 	if err != nil {
-		return models.PullRequest{}, "", err
+		return "", err
 	}
-	if len(cands) == 0 {
-		return models.PullRequest{}, "", ErrNoCandidate
+	if len(sel.picked) == 0 {
+		if len(sel.atCapacity) > 0 {
+			return "", ErrAtCapacity
+		}
+		return "", ErrNoCandidate
 	}
 
-	newID := pickNRandom(cands, 1)[0]
-	if err := s.r.ReplaceReviewerTx(ctx, tx, prID, oldUserID, newID); err != nil {
-		return models.PullRequest{}, "", err
+	newID := sel.picked[0]
+	if err := s.r.ReplaceReviewerTx(ctx, tx, pr.PullRequestID, oldUserID, newID); err != nil {
+		return "", err
 	}
-	if err := s.r.LogAssignmentsTx(ctx, tx, prID, []string{newID}, "REASSIGN"); err != nil {
-		return models.PullRequest{}, "", err
+	if err := s.r.LogAssignmentsTx(ctx, tx, pr.PullRequestID, []string{newID}, action); err != nil {
+		return "", err
 	}
+	return newID, nil
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		return models.PullRequest{}, "", err
+// PRReview records that userID has reviewed prID and returns the time of
+// their first review; repeating it keeps that time.
+func (s *Service) PRReview(ctx context.Context, prID, userID string) (models.PullRequest, time.Time, error) {
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.PullRequest{}, time.Time{}, err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	updated, err := s.r.GetPR(ctx, prID)
-	return updated, newID, err
-}
+	if _, err := s.r.GetPRForUpdateTx(ctx, tx, prID); err != nil {
+		return models.PullRequest{}, time.Time{}, notFound(err)
+	}
+	at, err := s.r.MarkReviewedTx(ctx, tx, prID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.PullRequest{}, time.Time{}, ErrNotAssigned
+	}
+	if err != nil {
+		return models.PullRequest{}, time.Time{}, err
+	}
 
-// -------- Stats --------
+	if err := tx.Commit(ctx); err != nil {
+		return models.PullRequest{}, time.Time{}, err
+	}
+	pr, err := s.r.GetPR(ctx, prID)
+	return pr, at, err
+}
 
-func (s *Service) StatsByUsers(ctx context.Context) ([]repo.UserAssignStat, error) {
-	return s.r.StatsByUsers(ctx)
+func (s *Service) PRGet(ctx context.Context, prID string) (models.PullRequest, error) {
+	pr, err := s.r.GetPR(ctx, prID)
+	if err != nil {
+		return models.PullRequest{}, notFound(err)
+	}
+	return pr, nil
 }
 
-func (s *Service) StatsByPRs(ctx context.Context) ([]repo.PRAssignStat, error) {
-	return s.r.StatsByPRs(ctx)
+func (s *Service) PRList(ctx context.Context, f repo.PRFilter, p repo.Page) ([]models.PullRequest, string, error) {
+	return s.r.ListPRs(ctx, f, p)
 }
 
-// -------- helpers --------
+// -------- Stats --------
 
-func pickNRandom(ids []string, n int) []string {
-	if n <= 0 || len(ids) == 0 {
-		return nil
-	}
-	if len(ids) <= n {
-		out := append([]string{}, ids...)
-		return out
-	}
-	out := append([]string{}, ids...)
-	for i := len(out) - 1; i > 0; i-- {
-		j := cryptoRandInt(i + 1)
-		out[i], out[j] = out[j], out[i]
-	}
-	return out[:n]
+func (s *Service) StatsByUsers(ctx context.Context, p repo.Page) ([]repo.UserAssignStat, string, error) {
+	return s.r.StatsByUsers(ctx, p)
 }
 
-func cryptoRandInt(upper int) int {
-	if upper <= 1 {
-		return 0
-	}
-	nBig, _ := crand.Int(crand.Reader, big.NewInt(int64(upper)))
-	return int(nBig.Int64())
+func (s *Service) StatsByPRs(ctx context.Context, p repo.Page) ([]repo.PRAssignStat, string, error) {
+	return s.r.StatsByPRs(ctx, p)
 }
 
-func ToHTTPError(err error) (code string, msg string, httpCode int) {
-	switch {
-	case errors.Is(err, ErrTeamExists):
-		return "TEAM_EXISTS", "team_name already exists", 400
-	case errors.Is(err, ErrPRExists):
-		return "PR_EXISTS", "PR id already exists", 409
-	case errors.Is(err, ErrPRMerged):
-		return "PR_MERGED", "cannot reassign on merged PR", 409
-	case errors.Is(err, ErrNotAssigned):
-		return "NOT_ASSIGNED", "reviewer is not assigned to this PR", 409
-	case errors.Is(err, ErrNoCandidate):
-		return "NO_CANDIDATE", "no active replacement candidate in team", 409
-	case errors.Is(err, ErrNotFound):
-		return "NOT_FOUND", "resource not found", 404
-	default:
-		return "NOT_FOUND", "internal error", 500
+// -------- helpers --------
+
+// ValidateMembers checks that every member has an id and a name and that ids are unique.
+func ValidateMembers(members []models.TeamMember) error {
+	var v Validation
+	seen := map[string]bool{}
+	for i, m := range members {
+		prefix := "members[" + strconv.Itoa(i) + "]."
+		v.Required(prefix+"user_id", m.UserID)
+		if m.UserID != "" && seen[m.UserID] {
+			v.Add(prefix+"user_id", "duplicate user_id")
+		}
+		seen[m.UserID] = true
+		v.Required(prefix+"username", m.Username)
 	}
+	return v.Err()
 }
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"reviewer-service/internal/models"
+)
+
+const (
+	maxTags           = 20
+	maxReviewerRules  = 10
+	maxBlockedAuthors = 50
+)
+
+// tagRe is the form of levels, skills and labels once lowercased.
+var tagRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._+#-]{0,63}$`)
+
+// TeamSettingsPatch lists the settings to change; nil fields are kept and
+// zero turns a setting off. ReviewerRules replaces all rules of the team.
+type TeamSettingsPatch struct {
+	ReviewSLASeconds     *int
+	EscalateAfterSeconds *int
+	AssignmentStrategy   *models.AssignmentStrategy
+	ReviewerRules        *[]models.ReviewerRule
+	PairAvoidancePRs     *int
+	PreferWorkingHours   *bool
+}
+
+func (s *Service) TeamGetSettings(ctx context.Context, team string) (models.TeamSettings, error) {
+	ts, err := s.r.GetTeamSettings(ctx, team)
+	if err != nil {
+		return models.TeamSettings{}, notFound(err)
+	}
+	return ts, nil
+}
+
+// TeamSetSettings applies p to the settings of team. Escalation must come
+// later than the reminder when both are on.
+func (s *Service) TeamSetSettings(ctx context.Context, team string, p TeamSettingsPatch) (models.TeamSettings, error) {
+	var v Validation
+	for _, f := range []struct {
+		name string
+		v    *int
+	}{{"review_sla_seconds", p.ReviewSLASeconds}, {"escalate_after_seconds", p.EscalateAfterSeconds}} {
+		if f.v != nil && *f.v < 0 {
+			v.Add(f.name, "must not be negative")
+		}
+	}
+	if n := p.PairAvoidancePRs; n != nil && (*n < 0 || *n > MaxPairAvoidancePRs) {
+		v.Add("pair_avoidance_prs", fmt.Sprintf("must be between 0 and %d", MaxPairAvoidancePRs))
+	}
+	if st := p.AssignmentStrategy; st != nil && *st != models.StrategyRandom && *st != models.StrategyRoundRobin {
+		v.Add("assignment_strategy", "must be RANDOM or ROUND_ROBIN")
+	}
+	var rules []models.ReviewerRule
+	if p.ReviewerRules != nil {
+		var err error
+		if rules, err = normalizeRules(*p.ReviewerRules); v.Merge(err) != nil {
+			return models.TeamSettings{}, err
+		}
+	}
+	if err := v.Err(); err != nil {
+		return models.TeamSettings{}, err
+	}
+
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.TeamSettings{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ts, err := s.r.GetTeamSettingsForUpdateTx(ctx, tx, team)
+	if err != nil {
+		return models.TeamSettings{}, notFound(err)
+	}
+	if p.ReviewSLASeconds != nil {
+		ts.ReviewSLASeconds = positive(*p.ReviewSLASeconds)
+	}
+	if p.EscalateAfterSeconds != nil {
+		ts.EscalateAfterSeconds = positive(*p.EscalateAfterSeconds)
+	}
+	if p.AssignmentStrategy != nil {
+		ts.AssignmentStrategy = *p.AssignmentStrategy
+	}
+	if p.ReviewerRules != nil {
+		ts.ReviewerRules = rules
+	}
+	if p.PairAvoidancePRs != nil {
+		ts.PairAvoidancePRs = positive(*p.PairAvoidancePRs)
+	}
+	if p.PreferWorkingHours != nil {
+		ts.PreferWorkingHours = *p.PreferWorkingHours
+	}
+	if ts.ReviewSLASeconds != nil && ts.EscalateAfterSeconds != nil && *ts.EscalateAfterSeconds <= *ts.ReviewSLASeconds {
+		return models.TeamSettings{}, Invalid("escalate_after_seconds", "must be greater than review_sla_seconds")
+	}
+
+	if err := s.r.SetTeamSettingsTx(ctx, tx, ts); err != nil {
+		return models.TeamSettings{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.TeamSettings{}, err
+	}
+	return ts, nil
+}
+
+// UserSettingsPatch lists the settings to change; nil fields are kept and
+// zero removes a limit. A zero ReviewWeight keeps the user from being picked,
+// an empty Level removes the level; Skills, BlockedAuthors and BlockedLabels
+// replace the whole list. An empty Timezone means UTC; empty WorkStart and
+// WorkEnd remove the working hours.
+type UserSettingsPatch struct {
+	MaxOpenReviews *int
+	ReviewWeight   *int
+	Level          *string
+	Skills         *[]string
+	BlockedAuthors *[]string
+	BlockedLabels  *[]string
+	Timezone       *string
+	WorkStart      *string
+	WorkEnd        *string
+}
+
+func (s *Service) UserGetSettings(ctx context.Context, userID string) (models.UserSettings, error) {
+	us, err := s.r.GetUserSettings(ctx, userID)
+	if err != nil {
+		return models.UserSettings{}, notFound(err)
+	}
+	return us, nil
+}
+
+// UserSetSettings applies p to the settings of userID. Lowering
+// max_open_reviews below the user's current open reviews keeps those; the
+// user just gets no new ones until under the limit. Blocking an author or a
+// label does not take the user off PRs they already review. Working hours
+// need both ends; an end before the start spans midnight.
+func (s *Service) UserSetSettings(ctx context.Context, userID string, p UserSettingsPatch) (models.UserSettings, error) {
+	var v Validation
+	if p.MaxOpenReviews != nil && *p.MaxOpenReviews < 0 {
+		v.Add("max_open_reviews", "must not be negative")
+	}
+	if p.ReviewWeight != nil && (*p.ReviewWeight < 0 || *p.ReviewWeight > MaxReviewWeight) {
+		v.Add("review_weight", fmt.Sprintf("must be between 0 and %d", MaxReviewWeight))
+	}
+	var level *string
+	if p.Level != nil && *p.Level != "" {
+		l := strings.ToLower(strings.TrimSpace(*p.Level))
+		if !tagRe.MatchString(l) {
+			v.Add("level", "must be a tag of letters, digits and ._+#-, up to 64 characters")
+		}
+		level = &l
+	}
+	var skills, blockedAuthors, blockedLabels []string
+	if p.Skills != nil {
+		var err error
+		if skills, err = normalizeTags("skills", *p.Skills); v.Merge(err) != nil {
+			return models.UserSettings{}, err
+		}
+	}
+	if p.BlockedAuthors != nil {
+		var err error
+		if blockedAuthors, err = s.checkBlockedAuthors(ctx, userID, *p.BlockedAuthors); v.Merge(err) != nil {
+			return models.UserSettings{}, err
+		}
+	}
+	if p.BlockedLabels != nil {
+		var err error
+		if blockedLabels, err = normalizeTags("blocked_labels", *p.BlockedLabels); v.Merge(err) != nil {
+			return models.UserSettings{}, err
+		}
+	}
+	if p.Timezone != nil && *p.Timezone != "" {
+		if _, err := time.LoadLocation(*p.Timezone); err != nil || *p.Timezone == "Local" {
+			v.Add("timezone", "must be an IANA time zone, e.g. Europe/Berlin")
+		}
+	}
+	for _, f := range []struct {
+		name string
+		v    *string
+	}{{"work_start", p.WorkStart}, {"work_end", p.WorkEnd}} {
+		if f.v != nil && *f.v != "" {
+			if _, ok := parseClock(*f.v); !ok {
+				v.Add(f.name, "must be a time of day as HH:MM")
+			}
+		}
+	}
+	if err := v.Err(); err != nil {
+		return models.UserSettings{}, err
+	}
+
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := s.lockUserWithTeamTx(ctx, tx, userID); err != nil {
+		return models.UserSettings{}, err
+	}
+	us, err := s.r.GetUserSettingsForUpdateTx(ctx, tx, userID)
+	if err != nil {
+		return models.UserSettings{}, notFound(err)
+	}
+	if p.MaxOpenReviews != nil {
+		us.MaxOpenReviews = positive(*p.MaxOpenReviews)
+	}
+	if p.ReviewWeight != nil {
+		us.ReviewWeight = *p.ReviewWeight
+	}
+	if p.Level != nil {
+		us.Level = level
+	}
+	if p.Skills != nil {
+		us.Skills = skills
+	}
+	if p.BlockedAuthors != nil {
+		us.BlockedAuthors = blockedAuthors
+	}
+	if p.BlockedLabels != nil {
+		us.BlockedLabels = blockedLabels
+	}
+	if p.Timezone != nil {
+		us.Timezone = nonEmpty(*p.Timezone)
+	}
+	if p.WorkStart != nil {
+		us.WorkStart = nonEmpty(*p.WorkStart)
+	}
+	if p.WorkEnd != nil {
+		us.WorkEnd = nonEmpty(*p.WorkEnd)
+	}
+	switch {
+	case (us.WorkStart == nil) != (us.WorkEnd == nil):
+		return models.UserSettings{}, Invalid("work_end", "working hours need both work_start and work_end")
+	case us.WorkStart != nil && *us.WorkStart == *us.WorkEnd:
+		return models.UserSettings{}, Invalid("work_end", "must differ from work_start")
+	}
+
+	if err := s.r.SetUserSettingsTx(ctx, tx, us); err != nil {
+		return models.UserSettings{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.UserSettings{}, err
+	}
+	return us, nil
+}
+
+// parseClock reads a time of day written as HH:MM and returns it in minutes
+// since midnight.
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil || len(s) != 5 {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// checkBlockedAuthors drops duplicates from ids and checks that they are
+// existing users other than userID; it never returns nil.
+func (s *Service) checkBlockedAuthors(ctx context.Context, userID string, ids []string) ([]string, error) {
+	if len(ids) > maxBlockedAuthors {
+		return nil, Invalid("blocked_authors", fmt.Sprintf("at most %d allowed", maxBlockedAuthors))
+	}
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		switch id = strings.TrimSpace(id); {
+		case id == "":
+			return nil, Invalid("blocked_authors", "must not contain empty ids")
+		case id == userID:
+			return nil, Invalid("blocked_authors", "must not contain the user")
+		case !slices.Contains(res, id):
+			res = append(res, id)
+		}
+	}
+	if len(res) == 0 {
+		return res, nil
+	}
+	found, err := s.r.ListUsersByIDs(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range res {
+		if !slices.ContainsFunc(found, func(u models.User) bool { return u.UserID == id }) {
+			return nil, Invalid("blocked_authors", fmt.Sprintf("unknown user %q", id))
+		}
+	}
+	return res, nil
+}
+
+// normalizeTags lowercases tags and drops duplicates; it never returns nil.
+func normalizeTags(field string, tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, Invalid(field, fmt.Sprintf("at most %d allowed", maxTags))
+	}
+	res := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !tagRe.MatchString(t) {
+			return nil, Invalid(field, fmt.Sprintf("%q is not a tag of letters, digits and ._+#-, up to 64 characters", t))
+		}
+		if !slices.Contains(res, t) {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// normalizeRules checks and lowercases reviewer rules; it never returns nil.
+func normalizeRules(rules []models.ReviewerRule) ([]models.ReviewerRule, error) {
+	if len(rules) > maxReviewerRules {
+		return nil, Invalid("reviewer_rules", fmt.Sprintf("at most %d allowed", maxReviewerRules))
+	}
+	res := make([]models.ReviewerRule, 0, len(rules))
+	for i, r := range rules {
+		field := fmt.Sprintf("reviewer_rules[%d]", i)
+		if (r.Level == "") == (r.Skill == "") {
+			return nil, Invalid(field, "exactly one of level and skill must be set")
+		}
+		var tags []string
+		for _, t := range []string{r.Level, r.Skill, r.IfLabel} {
+			if t = strings.ToLower(strings.TrimSpace(t)); t != "" && !tagRe.MatchString(t) {
+				return nil, Invalid(field, fmt.Sprintf("%q is not a tag of letters, digits and ._+#-, up to 64 characters", t))
+			}
+			tags = append(tags, t)
+		}
+		r = models.ReviewerRule{Level: tags[0], Skill: tags[1], IfLabel: tags[2]}
+		if !slices.Contains(res, r) {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+// positive maps 0 ("off") to nil.
+func positive(v int) *int {
+	if v <= 0 {
+		return nil
+	}
+	return &v
+}
+
+// nonEmpty maps "" ("off") to nil.
+func nonEmpty(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
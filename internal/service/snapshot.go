@@ -0,0 +1,287 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"reviewer-service/internal/repo"
+)
+
+// A snapshot is NDJSON: a header line, then one line per row grouped by type
+// in dependency order (team, user, pull_request, reviewer, assignment), then
+// a footer with the row counts. Every line is {"type": ..., "data": {...}};
+// row data uses the column names (see repo.Snapshot*). A missing footer means
+// the export was cut short.
+
+const (
+	SnapshotFormat  = "reviewer-service-snapshot"
+	SnapshotVersion = 10
+
+	// Older versions lack only nullable or defaulted columns and restore as
+	// is; version 1 reviewers without assigned_at get their PR's created_at.
+	snapshotMinVersion = 1
+
+	snapshotBatch   = 1000
+	snapshotMaxLine = 1 << 20
+)
+
+var snapshotTypes = []string{"header", "team", "user", "pull_request", "reviewer", "assignment", "footer"}
+
+type SnapshotHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SnapshotCounts struct {
+	Teams        int64 `json:"teams"`
+	Users        int64 `json:"users"`
+	PullRequests int64 `json:"pull_requests"`
+	Reviewers    int64 `json:"reviewers"`
+	Assignments  int64 `json:"assignments"`
+}
+
+type snapshotFooter struct {
+	Counts SnapshotCounts `json:"counts"`
+}
+
+type snapshotLine struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Export writes a snapshot of the whole database to w. It reads from a single
+// repeatable-read transaction, so the snapshot is consistent while writes go on.
+func (s *Service) Export(ctx context.Context, w io.Writer) (SnapshotCounts, error) {
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return SnapshotCounts{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	emit := func(typ string, data any) error {
+		return enc.Encode(struct {
+			Type string `json:"type"`
+			Data any    `json:"data"`
+		}{typ, data})
+	}
+
+	var c SnapshotCounts
+	err = emit("header", SnapshotHeader{Format: SnapshotFormat, Version: SnapshotVersion, CreatedAt: time.Now().UTC()})
+	if err == nil {
+		err = s.r.ExportTeamsTx(ctx, tx, func(v repo.SnapshotTeam) error { c.Teams++; return emit("team", v) })
+	}
+	if err == nil {
+		err = s.r.ExportUsersTx(ctx, tx, func(v repo.SnapshotUser) error { c.Users++; return emit("user", v) })
+	}
+	if err == nil {
+		err = s.r.ExportPRsTx(ctx, tx, func(v repo.SnapshotPR) error { c.PullRequests++; return emit("pull_request", v) })
+	}
+	if err == nil {
+		err = s.r.ExportReviewersTx(ctx, tx, func(v repo.SnapshotReviewer) error { c.Reviewers++; return emit("reviewer", v) })
+	}
+	if err == nil {
+		err = s.r.ExportAssignmentsTx(ctx, tx, func(v repo.SnapshotAssignment) error { c.Assignments++; return emit("assignment", v) })
+	}
+	if err == nil {
+		err = emit("footer", snapshotFooter{Counts: c})
+	}
+	if err != nil {
+		return c, err
+	}
+	return c, bw.Flush()
+}
+
+// RestoreSnapshot loads a snapshot written by Export into an empty database
+// in one transaction and returns the restored row counts. It fails with
+// ErrNotEmpty when any data is present.
+func (s *Service) RestoreSnapshot(ctx context.Context, r io.Reader) (SnapshotCounts, error) {
+	tx, err := s.r.Pool().BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return SnapshotCounts{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.r.LockSnapshotTablesTx(ctx, tx); err != nil {
+		return SnapshotCounts{}, err
+	}
+	empty, err := s.r.SnapshotTablesEmptyTx(ctx, tx)
+	if err != nil {
+		return SnapshotCounts{}, err
+	}
+	if !empty {
+		return SnapshotCounts{}, ErrNotEmpty
+	}
+
+	rs := &restorer{s: s, tx: tx}
+	if err := rs.run(ctx, r); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "23") {
+			return SnapshotCounts{}, Invalid("body", "inconsistent snapshot: "+pgErr.Message)
+		}
+		return SnapshotCounts{}, err
+	}
+	if err := s.r.ResetSequencesTx(ctx, tx); err != nil {
+		return SnapshotCounts{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return SnapshotCounts{}, err
+	}
+	return rs.counts, nil
+}
+
+// restorer buffers rows and copies them in batches. Rows arrive grouped by
+// type in dependency order, so flushing all buffers in that order is safe.
+type restorer struct {
+	s      *Service
+	tx     pgx.Tx
+	counts SnapshotCounts
+
+	teams       []repo.SnapshotTeam
+	users       []repo.SnapshotUser
+	prs         []repo.SnapshotPR
+	reviewers   []repo.SnapshotReviewer
+	assignments []repo.SnapshotAssignment
+}
+
+func (rs *restorer) run(ctx context.Context, r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), snapshotMaxLine)
+
+	rank := -1
+	line := 0
+	var footer *snapshotFooter
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		bad := func(format string, args ...any) error {
+			return Invalid("body", fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+		}
+		if footer != nil {
+			return bad("data after footer")
+		}
+
+		var l snapshotLine
+		if err := json.Unmarshal(raw, &l); err != nil {
+			return bad("malformed JSON: %v", err)
+		}
+		next := slices.Index(snapshotTypes, l.Type)
+		switch {
+		case next < 0:
+			return bad("unknown record type %q", l.Type)
+		case rank < 0 && l.Type != "header":
+			return bad("snapshot must start with a header")
+		case next < rank || (next == rank && l.Type == "header"):
+			return bad("%s record out of order", l.Type)
+		}
+		if next != rank {
+			if err := rs.flush(ctx); err != nil {
+				return err
+			}
+			rank = next
+		}
+
+		var err error
+		switch l.Type {
+		case "header":
+			var h SnapshotHeader
+			if err = decodeStrict(l.Data, &h); err == nil {
+				if h.Format != SnapshotFormat {
+					return bad("not a %s", SnapshotFormat)
+				}
+				if h.Version < snapshotMinVersion || h.Version > SnapshotVersion {
+					return bad("unsupported snapshot version %d", h.Version)
+				}
+			}
+		case "team":
+			err = decodeInto(l.Data, &rs.teams, &rs.counts.Teams)
+		case "user":
+			err = decodeInto(l.Data, &rs.users, &rs.counts.Users)
+		case "pull_request":
+			err = decodeInto(l.Data, &rs.prs, &rs.counts.PullRequests)
+		case "reviewer":
+			err = decodeInto(l.Data, &rs.reviewers, &rs.counts.Reviewers)
+		case "assignment":
+			err = decodeInto(l.Data, &rs.assignments, &rs.counts.Assignments)
+		case "footer":
+			footer = &snapshotFooter{}
+			err = decodeStrict(l.Data, footer)
+		}
+		if err != nil {
+			return bad("%s: %v", l.Type, err)
+		}
+		if rs.pending() >= snapshotBatch {
+			if err := rs.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Invalid("body", "cannot read snapshot: "+err.Error())
+	}
+	if footer == nil {
+		return Invalid("body", "snapshot has no footer, it is probably truncated")
+	}
+	if footer.Counts != rs.counts {
+		return Invalid("body", "row counts do not match the footer")
+	}
+	return rs.flush(ctx)
+}
+
+func (rs *restorer) pending() int {
+	return len(rs.teams) + len(rs.users) + len(rs.prs) + len(rs.reviewers) + len(rs.assignments)
+}
+
+func (rs *restorer) flush(ctx context.Context) error {
+	r, tx := rs.s.r, rs.tx
+	if err := r.RestoreTeamsTx(ctx, tx, rs.teams); err != nil {
+		return err
+	}
+	if err := r.RestoreUsersTx(ctx, tx, rs.users); err != nil {
+		return err
+	}
+	if err := r.RestorePRsTx(ctx, tx, rs.prs); err != nil {
+		return err
+	}
+	if err := r.RestoreReviewersTx(ctx, tx, rs.reviewers); err != nil {
+		return err
+	}
+	if err := r.RestoreAssignmentsTx(ctx, tx, rs.assignments); err != nil {
+		return err
+	}
+	rs.teams, rs.users, rs.prs = rs.teams[:0], rs.users[:0], rs.prs[:0]
+	rs.reviewers, rs.assignments = rs.reviewers[:0], rs.assignments[:0]
+	return nil
+}
+
+func decodeInto[T any](data json.RawMessage, dst *[]T, count *int64) error {
+	var v T
+	if err := decodeStrict(data, &v); err != nil {
+		return err
+	}
+	*dst = append(*dst, v)
+	*count++
+	return nil
+}
+
+func decodeStrict(data json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
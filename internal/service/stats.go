@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"reviewer-service/internal/repo"
+)
+
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+
+	maxStatsBuckets = 1000
+)
+
+// UserSeries is the assignment history of one reviewer. Points cover every
+// bucket of the window, empty ones with a zero count.
+type UserSeries struct {
+	UserID string            `json:"user_id"`
+	Total  int64             `json:"total"`
+	Points []repo.StatsPoint `json:"points"`
+}
+
+// TeamSeries is UserSeries summed over the current members of a team.
+type TeamSeries struct {
+	TeamName string            `json:"team_name"`
+	Total    int64             `json:"total"`
+	Points   []repo.StatsPoint `json:"points"`
+}
+
+// SeriesByUsers returns one page of reviewers with their assignments per
+// bucket (day, week or month, in UTC) between p.From and p.To.
+func (s *Service) SeriesByUsers(ctx context.Context, bucket string, p repo.Page) ([]UserSeries, string, error) {
+	if err := validateBucket(bucket, p); err != nil {
+		return nil, "", err
+	}
+	list, next, err := s.r.SeriesByUsers(ctx, bucket, p)
+	if err != nil {
+		return nil, "", err
+	}
+	points, err := fillBuckets(list, bucket, p)
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]UserSeries, len(list))
+	for i, sr := range list {
+		res[i] = UserSeries{UserID: sr.Key, Total: sr.Total, Points: points[i]}
+	}
+	return res, next, nil
+}
+
+// SeriesByTeams is SeriesByUsers per team; assignments count for the
+// reviewer's current team.
+func (s *Service) SeriesByTeams(ctx context.Context, bucket string, p repo.Page) ([]TeamSeries, string, error) {
+	if err := validateBucket(bucket, p); err != nil {
+		return nil, "", err
+	}
+	list, next, err := s.r.SeriesByTeams(ctx, bucket, p)
+	if err != nil {
+		return nil, "", err
+	}
+	points, err := fillBuckets(list, bucket, p)
+	if err != nil {
+		return nil, "", err
+	}
+	res := make([]TeamSeries, len(list))
+	for i, sr := range list {
+		res[i] = TeamSeries{TeamName: sr.Key, Total: sr.Total, Points: points[i]}
+	}
+	return res, next, nil
+}
+
+func (s *Service) StatsByTeams(ctx context.Context, p repo.Page) ([]repo.TeamAssignStat, string, error) {
+	return s.r.StatsByTeams(ctx, p)
+}
+
+// TeamFairness describes how evenly a team's assignments in a window are
+// spread over its active members. Gini is 0 for a perfectly even spread and
+// approaches 1 when one member gets everything; MaxMinRatio is nil when some
+// member got no assignments at all.
+type TeamFairness struct {
+	TeamName      string                `json:"team_name"`
+	ActiveMembers int                   `json:"active_members"`
+	Assignments   int64                 `json:"assignments"`
+	Mean          float64               `json:"mean"`
+	StdDev        float64               `json:"stddev"`
+	Gini          float64               `json:"gini"`
+	Min           int64                 `json:"min"`
+	Max           int64                 `json:"max"`
+	MaxMinRatio   *float64              `json:"max_min_ratio"`
+	Members       []repo.UserAssignStat `json:"members"`
+}
+
+// Fairness reports the spread of assignments in [from, to) per team (only
+// team when it is set). Only currently active members count; teams without
+// any are left out.
+func (s *Service) Fairness(ctx context.Context, team string, from, to *time.Time) ([]TeamFairness, error) {
+	loads, err := s.r.ActiveMemberLoads(ctx, team, from, to)
+	if err != nil {
+		return nil, err
+	}
+	res := []TeamFairness{}
+	for i := 0; i < len(loads); {
+		j := i
+		f := TeamFairness{TeamName: loads[i].TeamName}
+		for ; j < len(loads) && loads[j].TeamName == f.TeamName; j++ {
+			f.Members = append(f.Members, repo.UserAssignStat{UserID: loads[j].UserID, Count: loads[j].Count})
+		}
+		fillFairness(&f)
+		res = append(res, f)
+		i = j
+	}
+	return res, nil
+}
+
+func fillFairness(f *TeamFairness) {
+	counts := make([]int64, len(f.Members))
+	for i, m := range f.Members {
+		counts[i] = m.Count
+		f.Assignments += m.Count
+	}
+	slices.Sort(counts)
+	n := float64(len(counts))
+	f.ActiveMembers = len(counts)
+	f.Min, f.Max = counts[0], counts[len(counts)-1]
+	f.Mean = float64(f.Assignments) / n
+
+	var sq, weighted float64
+	for i, c := range counts {
+		d := float64(c) - f.Mean
+		sq += d * d
+		weighted += float64(i+1) * float64(c)
+	}
+	f.StdDev = math.Sqrt(sq / n)
+	if f.Assignments > 0 {
+		f.Gini = 2*weighted/(n*float64(f.Assignments)) - (n+1)/n
+	}
+	if f.Min > 0 {
+		ratio := float64(f.Max) / float64(f.Min)
+		f.MaxMinRatio = &ratio
+	}
+}
+
+// LatencyByTeams reports time to first review and time to merge of the PRs
+// created in the window, per author's team.
+func (s *Service) LatencyByTeams(ctx context.Context, f repo.LatencyFilter) ([]repo.TeamLatency, error) {
+	return s.r.LatencyByTeams(ctx, f)
+}
+
+// LatencyByReviewers reports the time from assignment to review of the
+// assignments made in the window, per reviewer.
+func (s *Service) LatencyByReviewers(ctx context.Context, f repo.LatencyFilter) ([]repo.ReviewerLatency, error) {
+	return s.r.LatencyByReviewers(ctx, f)
+}
+
+func validateBucket(bucket string, p repo.Page) error {
+	switch bucket {
+	case BucketDay, BucketWeek, BucketMonth:
+	default:
+		return Invalid("bucket", "must be day, week or month")
+	}
+	if p.From != nil && p.To != nil {
+		if n := countBuckets(bucketStart(*p.From, bucket), bucketStart(p.To.Add(-time.Nanosecond), bucket), bucket); n > maxStatsBuckets {
+			return tooManyBuckets()
+		}
+	}
+	return nil
+}
+
+// fillBuckets expands the sparse points of every series to the same dense
+// range of buckets: from p.From (or the earliest point on the page) to p.To
+// (or the latest point), so series can be plotted side by side.
+func fillBuckets(list []repo.Series, bucket string, p repo.Page) ([][]repo.StatsPoint, error) {
+	var first, last time.Time
+	for _, sr := range list {
+		if len(sr.Points) == 0 {
+			continue
+		}
+		if a := sr.Points[0].Start; first.IsZero() || a.Before(first) {
+			first = a
+		}
+		if b := sr.Points[len(sr.Points)-1].Start; b.After(last) {
+			last = b
+		}
+	}
+	if p.From != nil {
+		first = bucketStart(*p.From, bucket)
+	}
+	if p.To != nil {
+		last = bucketStart(p.To.Add(-time.Nanosecond), bucket)
+	}
+
+	res := make([][]repo.StatsPoint, len(list))
+	if first.IsZero() || last.IsZero() {
+		for i := range res {
+			res[i] = []repo.StatsPoint{}
+		}
+		return res, nil
+	}
+	n := countBuckets(first, last, bucket)
+	if n > maxStatsBuckets {
+		return nil, tooManyBuckets()
+	}
+	for i, sr := range list {
+		points := make([]repo.StatsPoint, 0, n)
+		j := 0
+		for t := first; !t.After(last); t = nextBucket(t, bucket) {
+			pt := repo.StatsPoint{Start: t}
+			if j < len(sr.Points) && sr.Points[j].Start.Equal(t) {
+				pt.Count = sr.Points[j].Count
+				j++
+			}
+			points = append(points, pt)
+		}
+		res[i] = points
+	}
+	return res, nil
+}
+
+// bucketStart truncates t like Postgres date_trunc does in UTC: weeks start
+// on Monday.
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case BucketWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case BucketMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return t.AddDate(0, 0, 7)
+	case BucketMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// countBuckets is the number of buckets from first to last, both included.
+func countBuckets(first, last time.Time, bucket string) int {
+	if last.Before(first) {
+		return 0
+	}
+	switch bucket {
+	case BucketWeek:
+		return int(last.Sub(first).Hours()/24/7) + 1
+	case BucketMonth:
+		return (last.Year()-first.Year())*12 + int(last.Month()-first.Month()) + 1
+	default:
+		return int(last.Sub(first).Hours()/24) + 1
+	}
+}
+
+func tooManyBuckets() error {
+	return Invalid("bucket", fmt.Sprintf("more than %d buckets in the window, narrow from/to or use a larger bucket", maxStatsBuckets))
+}
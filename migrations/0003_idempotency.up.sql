@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+  idem_key TEXT NOT NULL,
+  method TEXT NOT NULL,
+  path TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  status_code INT NULL,
+  response_body BYTEA NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  expires_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (idem_key, method, path)
+);
+
+CREATE INDEX idempotency_keys_expires_idx ON idempotency_keys(expires_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS prs_created_idx;
+DROP INDEX IF EXISTS review_assignments_created_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX review_assignments_created_idx ON review_assignments(created_at);
+CREATE INDEX prs_created_idx ON prs(created_at, pull_request_id);
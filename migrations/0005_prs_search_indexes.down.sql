@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_team_idx;
+DROP INDEX IF EXISTS prs_name_trgm_idx;
+DROP INDEX IF EXISTS prs_merged_idx;
+DROP INDEX IF EXISTS prs_status_created_idx;
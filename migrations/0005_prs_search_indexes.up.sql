@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX prs_status_created_idx ON prs(status, created_at, pull_request_id);
+CREATE INDEX prs_merged_idx ON prs(merged_at) WHERE merged_at IS NOT NULL;
+CREATE INDEX prs_name_trgm_idx ON prs USING gin (pull_request_name gin_trgm_ops);
+CREATE INDEX users_team_idx ON users(team_name);
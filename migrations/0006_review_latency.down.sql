@@ -0,0 +1,3 @@
+ALTER TABLE pr_reviewers
+  DROP COLUMN IF EXISTS reviewed_at,
+  DROP COLUMN IF EXISTS assigned_at;
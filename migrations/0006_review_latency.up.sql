@@ -0,0 +1,11 @@
+ALTER TABLE pr_reviewers
+  ADD COLUMN assigned_at TIMESTAMP NOT NULL DEFAULT now(),
+  ADD COLUMN reviewed_at TIMESTAMP NULL;
+
+-- Existing reviewers were assigned at their latest assignment log entry or,
+-- failing that, when the PR was created.
+UPDATE pr_reviewers prr SET assigned_at = COALESCE(
+  (SELECT MAX(ra.created_at) FROM review_assignments ra
+   WHERE ra.pull_request_id = prr.pull_request_id AND ra.assigned_user_id = prr.user_id),
+  (SELECT p.created_at FROM prs p WHERE p.pull_request_id = prr.pull_request_id)
+);
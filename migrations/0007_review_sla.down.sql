@@ -0,0 +1,10 @@
+DELETE FROM review_assignments WHERE action = 'ESCALATE_REASSIGN';
+ALTER TABLE review_assignments DROP CONSTRAINT review_assignments_action_check;
+ALTER TABLE review_assignments ADD CONSTRAINT review_assignments_action_check
+  CHECK (action IN ('AUTO_ASSIGN','REASSIGN','SAFE_REASSIGN'));
+
+DROP TABLE IF EXISTS review_reminders;
+
+ALTER TABLE teams
+  DROP COLUMN IF EXISTS escalate_after_seconds,
+  DROP COLUMN IF EXISTS review_sla_seconds;
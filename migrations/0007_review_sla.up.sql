@@ -0,0 +1,17 @@
+ALTER TABLE teams
+  ADD COLUMN review_sla_seconds INTEGER NULL CHECK (review_sla_seconds > 0),
+  ADD COLUMN escalate_after_seconds INTEGER NULL CHECK (escalate_after_seconds > 0);
+
+-- One row per reminder sent; assigned_at ties it to a single assignment, so a
+-- reassigned reviewer gets reminded again.
+CREATE TABLE review_reminders (
+  pull_request_id TEXT NOT NULL REFERENCES prs(pull_request_id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL REFERENCES users(user_id),
+  assigned_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (pull_request_id, user_id, assigned_at)
+);
+
+ALTER TABLE review_assignments DROP CONSTRAINT review_assignments_action_check;
+ALTER TABLE review_assignments ADD CONSTRAINT review_assignments_action_check
+  CHECK (action IN ('AUTO_ASSIGN','REASSIGN','SAFE_REASSIGN','ESCALATE_REASSIGN'));
@@ -0,0 +1,15 @@
+-- History of background job runs. A RUNNING row whose job lock is free was
+-- left behind by a crashed instance; the next run of that job fails it.
+CREATE TABLE job_runs (
+  id BIGSERIAL PRIMARY KEY,
+  job TEXT NOT NULL,
+  trigger TEXT NOT NULL CHECK (trigger IN ('SCHEDULE','MANUAL')),
+  instance TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'RUNNING' CHECK (status IN ('RUNNING','SUCCEEDED','FAILED')),
+  started_at TIMESTAMP NOT NULL DEFAULT now(),
+  finished_at TIMESTAMP NULL,
+  result JSONB NULL,
+  error TEXT NULL
+);
+
+CREATE INDEX job_runs_job_idx ON job_runs(job, id DESC);
@@ -0,0 +1,3 @@
+-- NULL means no limit.
+ALTER TABLE users
+  ADD COLUMN max_open_reviews INTEGER NULL CHECK (max_open_reviews > 0);
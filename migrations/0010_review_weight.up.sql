@@ -0,0 +1,3 @@
+-- Relative chance of being picked as a reviewer; 0 means never.
+ALTER TABLE users
+  ADD COLUMN review_weight INTEGER NOT NULL DEFAULT 1 CHECK (review_weight BETWEEN 0 AND 100);
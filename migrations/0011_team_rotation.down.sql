@@ -0,0 +1,3 @@
+ALTER TABLE teams
+  DROP COLUMN IF EXISTS rotation_cursor,
+  DROP COLUMN IF EXISTS assignment_strategy;
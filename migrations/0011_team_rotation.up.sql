@@ -0,0 +1,6 @@
+-- rotation_cursor is the member picked last by ROUND_ROBIN; the next pick is
+-- the first eligible member after it in user_id order.
+ALTER TABLE teams
+  ADD COLUMN assignment_strategy TEXT NOT NULL DEFAULT 'RANDOM'
+    CHECK (assignment_strategy IN ('RANDOM', 'ROUND_ROBIN')),
+  ADD COLUMN rotation_cursor TEXT NULL;
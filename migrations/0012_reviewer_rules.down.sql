@@ -0,0 +1,5 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS reviewer_rules;
+ALTER TABLE prs DROP COLUMN IF EXISTS labels;
+ALTER TABLE users
+  DROP COLUMN IF EXISTS skills,
+  DROP COLUMN IF EXISTS level;
@@ -0,0 +1,11 @@
+ALTER TABLE users
+  ADD COLUMN level TEXT NULL,
+  ADD COLUMN skills TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE prs
+  ADD COLUMN labels TEXT[] NOT NULL DEFAULT '{}';
+
+-- A JSON array of {"level"|"skill", "if_label"?} objects; every rule that
+-- applies to a PR needs at least one matching reviewer.
+ALTER TABLE teams
+  ADD COLUMN reviewer_rules JSONB NOT NULL DEFAULT '[]';
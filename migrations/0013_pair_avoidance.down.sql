@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS prs_author_created_idx;
+ALTER TABLE teams DROP COLUMN IF EXISTS pair_avoidance_prs;
@@ -0,0 +1,6 @@
+-- pair_avoidance_prs is how many of the author's latest PRs selection looks
+-- back at to avoid reviewers who were assigned to them; NULL turns it off.
+ALTER TABLE teams
+  ADD COLUMN pair_avoidance_prs INTEGER NULL CHECK (pair_avoidance_prs BETWEEN 1 AND 100);
+
+CREATE INDEX prs_author_created_idx ON prs(author_id, created_at, pull_request_id);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP COLUMN IF EXISTS blocked_labels,
+  DROP COLUMN IF EXISTS blocked_authors;
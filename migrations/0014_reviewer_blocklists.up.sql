@@ -0,0 +1,5 @@
+-- A user is never picked to review PRs by blocked_authors or PRs carrying
+-- any of blocked_labels.
+ALTER TABLE users
+  ADD COLUMN blocked_authors TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN blocked_labels TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS prefer_working_hours;
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS users_working_hours_check,
+  DROP COLUMN IF EXISTS work_end,
+  DROP COLUMN IF EXISTS work_start,
+  DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,10 @@
+-- Working hours are local "HH:MM" times in the user's IANA timezone (UTC when
+-- NULL); work_end before work_start spans midnight.
+ALTER TABLE users
+  ADD COLUMN timezone TEXT NULL,
+  ADD COLUMN work_start TEXT NULL CHECK (work_start ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+  ADD COLUMN work_end TEXT NULL CHECK (work_end ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+  ADD CONSTRAINT users_working_hours_check CHECK ((work_start IS NULL) = (work_end IS NULL));
+
+ALTER TABLE teams
+  ADD COLUMN prefer_working_hours BOOLEAN NOT NULL DEFAULT false;